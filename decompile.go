@@ -0,0 +1,569 @@
+package simpex
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// String returns the pattern of the Simpex, in a canonical form that compiles
+// back into an identical Simpex with the same options. Symbols are escaped
+// where needed, optional groups are written with ?, and anything matched a
+// number of times in a row is written with bounds, however it was originally
+// written.
+func (sx Simpex) String() string {
+	var b strings.Builder
+
+	sx.decompile(&b, 0, len(sx.insts))
+
+	return b.String()
+}
+
+// decompile writes the pattern of the instructions between positions start
+// and end, which must not split up any groups.
+func (sx Simpex) decompile(b *strings.Builder, start, end int) {
+	// Whether bounds were just written, after which neither '?' nor '<'
+	// have any special meaning, and whether what was just written can't
+	// be followed by some symbols.
+	bounded, uncombinable := false, false
+
+	// Where the colon is that needs doubling, for text at the start of
+	// an unnamed capture not to be taken for its name.
+	colonpc, colon := -1, -1
+
+	for pc := start; pc < end; {
+		if next, unit, ok := sx.decompileRepetition(b, pc, end, uncombinable); ok {
+			pc, bounded, uncombinable = next, true, sx.insts[unit].op != opGroupStart
+			continue
+		}
+
+		in := sx.insts[pc]
+
+		switch in.op {
+		case opLiteral:
+			doubled := -1
+			if pc == colonpc {
+				doubled = colon
+			}
+
+			uncombinable = sx.decompileLiteral(b, pc, doubled, bounded, uncombinable)
+
+		case opCaptureStart:
+			b.WriteByte('{')
+			if name := sx.names[in.arg]; name != "" {
+				b.WriteString("?" + name + ":")
+			} else {
+				colonpc, colon = sx.namecolon(pc + 1)
+			}
+
+		case opCaptureEnd:
+			b.WriteByte('}')
+
+		case opGroupStart:
+			pc, bounded, uncombinable = sx.decompileGroup(b, pc), false, false
+			continue
+
+		case opAlternation:
+			b.WriteByte('|')
+			uncombinable = false
+
+		case opChar:
+			b.WriteByte('_')
+			uncombinable = true
+
+		case opWord:
+			b.WriteByte('^')
+			uncombinable = true
+
+		case opNumber:
+			b.WriteByte('#')
+			uncombinable = true
+
+		case opSpace:
+			b.WriteByte(sx.space(pc, end))
+			uncombinable = false
+
+		case opClass:
+			sx.decompileClass(b, *in.class)
+			uncombinable = false
+
+		case opPhrase:
+			p := in.phrase
+
+			b.WriteByte('*')
+			if p.greedy {
+				b.WriteByte('+')
+			}
+
+			pc, bounded, uncombinable = pc+1, false, true
+
+			switch {
+			case p.low == 1 && p.high == -1:
+
+			case p.high == -1:
+				b.WriteString("<" + strconv.Itoa(p.low) + ",>")
+				bounded = true
+
+			default:
+				writebounds(b, p.low, p.high)
+				bounded = true
+			}
+
+			continue
+		}
+
+		pc, bounded = pc+1, false
+	}
+}
+
+// decompileLiteral writes the pattern of the literal text at position pc of
+// the instructions, with the colon at position colon of it doubled, returning
+// whether what it ends with can't be followed by some symbols.
+func (sx Simpex) decompileLiteral(b *strings.Builder, pc, colon int, bounded, uncombinable bool) bool {
+	literal := sx.insts[pc].literal
+
+	for i := 0; i < len(literal); {
+		char := literal[i]
+
+		switch char {
+		case '{', '}', '(', '|', '_', '^', '*', '#', '~', '[', ']':
+			b.WriteString(string([]byte{char, char}))
+
+		// Right after a group written ending in ')', the group is
+		// repeated once, as ))) would have been an escape ending it.
+		case ')':
+			if i == 0 && pc > 0 && sx.insts[pc-1].op == opGroupEnd && strings.HasSuffix(b.String(), ")") {
+				b.WriteString("<1>")
+			}
+
+			b.WriteString("))")
+
+		// These are only symbols right after some others, where a run of
+		// them is escaped as a whole.
+		case '?', '<', '+':
+			prev := op(opLiteral)
+			if i == 0 && pc > 0 {
+				prev = sx.insts[pc-1].op
+			}
+
+			run := i
+			for run < len(literal) && literal[run] == char {
+				run++
+			}
+
+			escaped := strings.Repeat(string(char), run-i)
+			if char == '?' && prev == opGroupEnd && !bounded ||
+				char == '<' && (prev == opChar || prev == opWord || prev == opGroupEnd || prev == opPhrase) && !bounded ||
+				char == '+' && prev == opPhrase && !bounded {
+				escaped += escaped
+			}
+
+			b.WriteString(escaped)
+			i, uncombinable = run, false
+
+			continue
+
+		case ':':
+			b.WriteByte(char)
+			if i == colon {
+				b.WriteByte(char)
+			}
+
+		default:
+			b.WriteByte(char)
+		}
+
+		// Captures make no difference to what can be combined.
+		if char != '{' && char != '}' {
+			uncombinable = char == '_' || char == '^' || char == '*' || char == '#'
+		}
+
+		i++
+	}
+
+	return uncombinable
+}
+
+// namecolon returns where the colon is in the literal text following position
+// pc of the instructions, if what comes before it would be written as a
+// question mark and a capture name, as the position of the literal text and
+// of the colon in it. Otherwise -1 is returned for both.
+func (sx Simpex) namecolon(pc int) (int, int) {
+	// How much has been written of what would be the name, counting the
+	// question mark leading it.
+	n := 0
+
+	for ; pc < len(sx.insts); pc++ {
+		in := sx.insts[pc]
+
+		// Single characters are written as underscores, which names can
+		// hold but not start with. Those repeated have bounds.
+		if in.op == opChar && n > 1 && pc+1 < len(sx.insts) && sx.insts[pc+1].op == opLiteral {
+			n++
+			continue
+		}
+
+		if in.op != opLiteral {
+			return -1, -1
+		}
+
+		for i, char := range in.literal {
+			if char == ':' && n > 1 {
+				return pc, i
+			}
+
+			if n == 0 && char != '?' ||
+				n == 1 && !isalpha(rune(char)) ||
+				n > 1 && !isalphanum(rune(char)) && char != '_' {
+				return -1, -1
+			}
+
+			n++
+		}
+	}
+
+	return -1, -1
+}
+
+// space returns the character to write the space symbol at position pc of the
+// instructions with. That's ~, except with loose spaces, where spaces and ~
+// right after one another are separate symbols and take turns, ending in a
+// space before any ~ that follows.
+func (sx Simpex) space(pc, end int) byte {
+	if !sx.opts.LooseSpaces {
+		return '~'
+	}
+
+	last := pc
+	for last+1 < end && sx.insts[last+1].op == opSpace {
+		last++
+	}
+
+	tilde := last+1 < end && sx.insts[last+1].op == opLiteral && sx.insts[last+1].literal[0] == '~'
+	if (last-pc)%2 == 0 == tilde {
+		return ' '
+	}
+
+	return '~'
+}
+
+// decompileGroup writes the pattern of the group starting at position pc of
+// the instructions, returning where it ends.
+func (sx Simpex) decompileGroup(b *strings.Builder, pc int) int {
+	close := sx.groupend(pc)
+
+	// Groups ending with an empty branch are optional, unless what comes
+	// before it is written ending in a group, where )) would have been an
+	// escape. Other groups ending in a group have it repeated once, for
+	// the same reason.
+	b.WriteByte('(')
+	if close-1 > pc && sx.insts[close-1].op == opAlternation {
+		sx.decompile(b, pc+1, close-1)
+		if sx.insts[close-2].op == opGroupEnd && strings.HasSuffix(b.String(), ")") {
+			b.WriteString("|)")
+		} else {
+			b.WriteString(")?")
+		}
+	} else {
+		sx.decompile(b, pc+1, close)
+		if sx.insts[close-1].op == opGroupEnd && strings.HasSuffix(b.String(), ")") {
+			b.WriteString("<1>")
+		}
+
+		b.WriteByte(')')
+	}
+
+	return close + 1
+}
+
+// decompileClass writes the pattern of a character class.
+func (sx Simpex) decompileClass(b *strings.Builder, c class) {
+	char := func(r rune) {
+		if r == ']' {
+			b.WriteString("]]")
+		} else if sx.opts.UTF8 {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte(byte(r))
+		}
+	}
+
+	b.WriteByte('[')
+	if c.negated {
+		b.WriteByte('^')
+	}
+
+	for i := 0; i < len(c.ranges); i += 2 {
+		char(c.ranges[i])
+
+		// Single characters are written as ranges of one before a dash,
+		// which would otherwise make a range of them, unless it's a dash
+		// at the end of the class.
+		dash := i+2 < len(c.ranges) && c.ranges[i+2] == '-' &&
+			(i+4 < len(c.ranges) || c.ranges[i+3] != '-')
+
+		if c.ranges[i+1] != c.ranges[i] || dash && c.ranges[i] != ']' {
+			b.WriteByte('-')
+			char(c.ranges[i+1])
+		}
+	}
+
+	b.WriteByte(']')
+}
+
+// decompileRepetition writes the pattern of a repetition starting at position
+// pc of the instructions, if there is one before position end, returning where
+// it ends and where its unit is. See repetition() for what they're expanded
+// into.
+//
+// Characters and words stay uncombinable with their bounds, so where they
+// come right after or before symbols they can't be combined with, any
+// optional ones are left as groups.
+func (sx Simpex) decompileRepetition(b *strings.Builder, pc, end int, uncombinable bool) (int, int, bool) {
+	unit, low, high, next := pc, 0, 0, pc
+
+	// Optional characters and words come first, as they're written without
+	// a group that might have followed right after the start of another.
+	if sx.optional(pc, end) {
+		unit = pc + 1
+		low, high, next = sx.bounds(pc, unit, 1, 0, end, !uncombinable)
+	}
+
+	if high == 0 {
+		if size, ok := sx.unit(pc); ok {
+			unit = pc
+			low, high, next = sx.bounds(pc, unit, size, 1, end, !uncombinable)
+		}
+	}
+
+	// Otherwise the whole repetition of a group might be optional.
+	if (high == 0 || low == 1 && high == 1) && sx.insts[pc].op == opGroupStart {
+		if size, ok := sx.unit(pc + 1); ok && sx.insts[pc+1].op == opGroupStart {
+			unit = pc + 1
+			low, high, next = sx.bounds(pc, unit, size, 0, end, true)
+		}
+	}
+
+	if high == 0 || low == 1 && high == 1 {
+		return 0, 0, false
+	}
+
+	switch sx.insts[unit].op {
+	case opChar:
+		b.WriteByte('_')
+
+	case opWord:
+		b.WriteByte('^')
+
+	case opGroupStart:
+		sx.decompileGroup(b, unit)
+	}
+
+	writebounds(b, low, high)
+
+	return next, unit, true
+}
+
+// writebounds writes the pattern of bounds between low and high.
+func writebounds(b *strings.Builder, low, high int) {
+	if low == high {
+		b.WriteString("<" + strconv.Itoa(low) + ">")
+	} else {
+		b.WriteString("<" + strconv.Itoa(low) + "," + strconv.Itoa(high) + ">")
+	}
+}
+
+// bounds returns the lowest and highest number of times the unit of the given
+// size at position unit of the instructions is repeated from position pc, at
+// least low times, and where that ends before position end. Optional
+// repetitions of characters and words are only counted if they can be
+// combined with what comes before and after.
+func (sx Simpex) bounds(pc, unit, size, low, end int, combinable bool) (int, int, int) {
+	// Words are separated by spaces.
+	separator := 0
+	if sx.insts[unit].op == opWord {
+		separator = 1
+	}
+
+	limit := maxRepeat
+	if maxExpansion/size < limit {
+		limit = maxExpansion / size
+	}
+
+	next := pc
+	if low > 0 {
+		next = unit + size
+		for low < limit && next+separator+size <= end &&
+			(separator == 0 || sx.insts[next].op == opSpace) &&
+			sx.sameunit(unit, next+separator, size) {
+			low++
+			next += separator + size
+		}
+	}
+
+	count, after := sx.nested(next, unit, size, low)
+	if count == 0 || low+count > limit {
+		return low, low, next
+	}
+
+	if sx.insts[unit].op != opGroupStart && (!combinable || sx.uncombinable(after, end)) {
+		return low, low, next
+	}
+
+	return low, low + count, after
+}
+
+// unit returns the size of what could be repeated at position pc of the
+// instructions, being a character or word symbol or a whole group without
+// captures. Groups starting with another group that's written as such are
+// left out, as (( would have been an escape.
+func (sx Simpex) unit(pc int) (int, bool) {
+	switch sx.insts[pc].op {
+	case opChar, opWord:
+		return 1, true
+
+	case opGroupStart:
+		close := sx.groupend(pc)
+		if sx.insts[pc+1].op == opGroupStart && !sx.optional(pc+1, close) {
+			return 0, false
+		}
+
+		for _, in := range sx.insts[pc:close] {
+			if in.op == opCaptureStart {
+				return 0, false
+			}
+		}
+
+		return close + 1 - pc, true
+	}
+
+	return 0, false
+}
+
+// optional tells whether there's a group at position pc of the instructions
+// that's written as optional characters or words, before position end and
+// following something they can be combined with.
+func (sx Simpex) optional(pc, end int) bool {
+	if pc+1 >= end || sx.insts[pc].op != opGroupStart ||
+		sx.insts[pc+1].op != opChar && sx.insts[pc+1].op != opWord {
+		return false
+	}
+
+	_, high, _ := sx.bounds(pc, pc+1, 1, 0, end, true)
+
+	return high > 0
+}
+
+// nested counts the optional groups nested in one another from position pc of
+// the instructions, each holding the nth repetition of the unit at position
+// unit, returning how many there are and where they end.
+func (sx Simpex) nested(pc, unit, size, n int) (int, int) {
+	if pc >= len(sx.insts) || sx.insts[pc].op != opGroupStart {
+		return 0, pc
+	}
+
+	next := pc + 1
+
+	if n > 0 && sx.insts[unit].op == opWord {
+		if next >= len(sx.insts) || sx.insts[next].op != opSpace {
+			return 0, pc
+		}
+
+		next++
+	}
+
+	if !sx.sameunit(unit, next, size) {
+		return 0, pc
+	}
+
+	count, next := sx.nested(next+size, unit, size, n+1)
+
+	if next+1 >= len(sx.insts) || sx.insts[next].op != opAlternation || sx.insts[next+1].op != opGroupEnd {
+		return 0, pc
+	}
+
+	return count + 1, next + 2
+}
+
+// sameunit tells whether the instructions hold identical units of the given
+// size at positions a and b, down to the classes and phrases they contain.
+func (sx Simpex) sameunit(a, b, size int) bool {
+	if b+size > len(sx.insts) {
+		return false
+	}
+
+	for i := 0; i < size; i++ {
+		x, y := sx.insts[a+i], sx.insts[b+i]
+		if x.op != y.op {
+			return false
+		}
+
+		switch x.op {
+		case opLiteral:
+			if !bytes.Equal(x.literal, y.literal) {
+				return false
+			}
+
+		case opGroupStart, opAlternation:
+			if x.arg-a != y.arg-b {
+				return false
+			}
+
+		case opClass:
+			if !reflect.DeepEqual(*x.class, *y.class) {
+				return false
+			}
+
+		case opPhrase:
+			if *x.phrase != *y.phrase {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// groupend returns the position of the end of the group starting at position
+// pc of the instructions.
+func (sx Simpex) groupend(pc int) int {
+	for sx.insts[pc].op != opGroupEnd {
+		pc = sx.insts[pc].arg
+	}
+
+	return pc
+}
+
+// uncombinable tells whether the instructions from position pc, before position
+// end, can't follow right after a character, word, number or phrase symbol,
+// the same as those symbols themselves can't follow one. Captures are looked
+// past.
+func (sx Simpex) uncombinable(pc, end int) bool {
+	for ; pc < end; pc++ {
+		switch in := sx.insts[pc]; in.op {
+		case opCaptureStart, opCaptureEnd:
+
+		case opChar, opWord, opNumber, opPhrase:
+			return true
+
+		case opLiteral:
+			for _, char := range in.literal {
+				switch char {
+				case '{', '}':
+
+				case '_', '^', '*', '#':
+					return true
+
+				default:
+					return false
+				}
+			}
+
+		default:
+			return false
+		}
+	}
+
+	return false
+}
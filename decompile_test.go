@@ -0,0 +1,162 @@
+package simpex_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tobiassjosten/go-simpex"
+)
+
+func TestString(t *testing.T) {
+	tcs := map[string]struct {
+		pattern string
+		opts    simpex.CompileOptions
+		string  string
+	}{
+		"literal": {
+			pattern: "Lorem ipsum",
+			string:  "Lorem ipsum",
+		},
+		"symbols": {
+			pattern: "{?name:^} {*} {_}~{#}",
+			string:  "{?name:^} {*} {_}~{#}",
+		},
+		"escaped names": {
+			pattern: "{?Say:: *} {?a__b::_} {?a_b::^} {::} {?n:a:b}",
+			string:  "{?Say:: *} {?a__b::_} {?a_b::^} {::} {?n:a:b}",
+		},
+		"unmarked names": {
+			pattern: "{Say: *} {1st: ^}",
+			string:  "{Say: *} {1st: ^}",
+		},
+		"escapes": {
+			pattern: "{{ }} (( || )) __ ^^ ** ## ~~ [[ ]]",
+			string:  "{{ }} (( || )) __ ^^ ** ## ~~ [[ ]]",
+		},
+		"escapes next to symbols": {
+			pattern: "{{{^}}} (((a|b)))",
+			string:  "{{{^}}} (((a|b)))",
+		},
+		"conditional escapes": {
+			pattern: "(a)?? ^<< *++ *<< ? < +",
+			string:  "(a)?? ^<< *++ *<< ? < +",
+		},
+		"optional group": {
+			pattern: "(a|b|)",
+			string:  "(a|b)?",
+		},
+		"optional group before literals": {
+			pattern: "(a)??<<",
+			string:  "(a)??<<",
+		},
+		"classes": {
+			pattern: "[a-z_] [^]]0-9] [-a] [a-] [ - -a]",
+			string:  "[a-z_] [^]]0-9] [-a] [a-] [ - -a]",
+		},
+		"utf-8 classes": {
+			pattern: "[å-ö]",
+			opts:    simpex.CompileOptions{UTF8: true},
+			string:  "[å-ö]",
+		},
+		"phrases": {
+			pattern: "*+ *<2,> *+<0,3> *<4,4>",
+			string:  "*+ *<2,> *+<0,3> *<4>",
+		},
+		"literals after phrase bounds": {
+			pattern: "*<2,>+<",
+			string:  "*<2,>+<",
+		},
+		"repetitions": {
+			pattern: "_<3> ^<1,3> (a|[bc])<0,2>",
+			string:  "_<3> ^<1,3> (a|[bc])<0,2>",
+		},
+		"literals after repetitions": {
+			pattern: "(a)<2>?<",
+			string:  "(a)<2>?<",
+		},
+		"spelled out repetitions": {
+			pattern: "^~^ (a)(a) _(_)?",
+			string:  "^<2> (a)<2> _<1,2>",
+		},
+		"different classes": {
+			pattern: "([a])([b])",
+			string:  "([a])([b])",
+		},
+		"repeated groups": {
+			pattern: "( (ab)<1,2>|c)<2>",
+			string:  "( (ab)<1,2>|c)<2>",
+		},
+		"groups ending in groups": {
+			pattern: "(0(0)<1>)0 a()<1>))",
+			string:  "(0(0)<1>)0 a()<1>))",
+		},
+		"symbol characters": {
+			pattern: "\x02{_}\x03 (\x1e|[\x1f])\x06?",
+			string:  "\x02{_}\x03 (\x1e|[\x1f])\x06?",
+		},
+		"loose spaces": {
+			pattern: "a ~ b~c",
+			opts:    simpex.CompileOptions{LooseSpaces: true},
+			string:  "a~ ~b~c",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := tc.opts.CompileString(tc.pattern)
+			if err != nil {
+				t.Fatalf("CompileString(%q) = %s", tc.pattern, err)
+			}
+
+			if s := sx.String(); s != tc.string {
+				t.Fatalf("String() = %q, want %q", s, tc.string)
+			}
+		})
+	}
+}
+
+// FuzzString verifies that patterns compile into identical Simpexes from
+// their String().
+func FuzzString(f *testing.F) {
+	f.Add([]byte("{?name:^} {*} {_}~{#}"), false)
+	f.Add([]byte("{{ }} (( || )) __ ^^ ** ## ~~ [[ ]]"), false)
+	f.Add([]byte("(a)?? ^<< *++ *<< (a|b|)"), false)
+	f.Add([]byte("[a-z_] [^]]0-9] [-a] [a-] [ - -a]"), false)
+	f.Add([]byte("*+ *<2,> *+<0,3> *<2,>++<"), false)
+	f.Add([]byte("_<3> ^<1,3> (a|[bc])<0,2> (a)<2>?<"), false)
+	f.Add([]byte("^~^ (a)(a) _(_)? ( (ab)<1,2>|c)<2>"), false)
+	f.Add([]byte("a ~ b~c"), true)
+	f.Add([]byte("{?Say:: *} {?a__b::_} {?a_b::^} {?a::::b}"), false)
+	f.Add([]byte("\x02{_}\x03 (\x1e|[\x1f])<2>\x06?"), false)
+	f.Add([]byte("_? ~~ ~ ~"), true)
+	f.Add([]byte(",++(|)*+(_|)))?))b"), false)
+	f.Add([]byte("#(_<1,3>|)(_|)(^|)_<2>(_)?"), false)
+	f.Add([]byte("(_)#{?n:(_)?}{_}(_)?(_)b **(_)?**"), false)
+	f.Add([]byte("( (a)<2>)<0,2>( (a)<2>)<0,2>(a)<0,1>(a)<0,1>"), false)
+	f.Add([]byte("*<2>(_<0,2>|)<0,2>(_<0,1>))[--z])<0,2>"), false)
+	f.Add([]byte("(0(0)<1>)0"), false)
+	f.Add([]byte("a()<1>))"), false)
+
+	f.Fuzz(func(t *testing.T, pattern []byte, loose bool) {
+		opts := simpex.CompileOptions{LooseSpaces: loose}
+
+		sx, err := opts.Compile(pattern)
+		if err != nil {
+			return
+		}
+
+		s := sx.String()
+
+		decompiled, err := opts.CompileString(s)
+		if err != nil {
+			t.Fatalf("CompileString(%q) = %s, from %q", s, err, pattern)
+		}
+
+		if !reflect.DeepEqual(sx, decompiled) {
+			t.Fatalf(
+				"CompileString(%q) = %q, want %q, from %q",
+				s, simpex.Program(decompiled), simpex.Program(sx), pattern,
+			)
+		}
+	})
+}
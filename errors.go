@@ -0,0 +1,154 @@
+package simpex
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrorKind is the kind of problem keeping a pattern from compiling.
+type ErrorKind int
+
+// Kinds of problems, as described by errorKinds.
+const (
+	ErrInvalidCombination ErrorKind = iota
+	ErrUnopenedCapture
+	ErrUnclosedCapture
+	ErrDuplicateName
+	ErrUnopenedGroup
+	ErrUnclosedGroup
+	ErrAlternationOutsideGroup
+	ErrUnopenedClass
+	ErrUnclosedClass
+	ErrEmptyClass
+	ErrInvalidRange
+	ErrInvalidRepetition
+	ErrUnclosedRepetition
+	ErrInvalidBounds
+	ErrRepetitionTooLarge
+	ErrCaptureInRepetition
+)
+
+var errorKinds = map[ErrorKind]string{
+	ErrInvalidCombination:      "invalid combination",
+	ErrUnopenedCapture:         "unopened capture",
+	ErrUnclosedCapture:         "unclosed capture",
+	ErrDuplicateName:           "duplicate capture name",
+	ErrUnopenedGroup:           "unopened group",
+	ErrUnclosedGroup:           "unclosed group",
+	ErrAlternationOutsideGroup: "alternation outside group",
+	ErrUnopenedClass:           "unopened class",
+	ErrUnclosedClass:           "unclosed class",
+	ErrEmptyClass:              "empty class",
+	ErrInvalidRange:            "invalid range",
+	ErrInvalidRepetition:       "invalid repetition",
+	ErrUnclosedRepetition:      "unclosed repetition",
+	ErrInvalidBounds:           "invalid repetition bounds",
+	ErrRepetitionTooLarge:      "repetition too large",
+	ErrCaptureInRepetition:     "capture in repeated group",
+}
+
+func (kind ErrorKind) String() string {
+	if s, ok := errorKinds[kind]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("ErrorKind(%d)", int(kind))
+}
+
+// Validate checks a pattern for every problem keeping it from compiling,
+// rather than stopping at the first one like Compile() does. They're returned
+// in order of their offsets in the pattern, or nil if it compiles.
+func Validate(pattern []byte) []*CompileError {
+	return CompileOptions{}.Validate(pattern)
+}
+
+// Validate is like the global Validate(), but with the given options.
+func (opts CompileOptions) Validate(pattern []byte) []*CompileError {
+	_, errs := opts.compile(pattern, true)
+
+	for _, err := range errs {
+		err.Pattern = string(pattern)
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Offset < errs[j].Offset
+	})
+
+	return errs
+}
+
+// CompileError describes why a pattern doesn't compile, as returned by
+// Compile() and its variants. Use errors.As() to get at it.
+type CompileError struct {
+	Kind ErrorKind
+
+	// Pattern is the whole pattern that didn't compile.
+	Pattern string
+
+	// Offset is the position of the offending byte in the pattern. For
+	// captures, groups, classes, and bounds left unclosed, that's the byte
+	// opening them.
+	Offset int
+
+	// Detail is what's at fault, if there's more to it than the byte at the
+	// offset, like the name of a duplicate capture.
+	Detail string
+}
+
+func (err *CompileError) Error() string {
+	if err.Detail != "" {
+		return fmt.Sprintf("%s '%s' at position %d", err.Kind, err.Detail, err.Offset)
+	}
+
+	return fmt.Sprintf("%s at position %d", err.Kind, err.Offset)
+}
+
+// Caret renders the pattern on one line and a caret under the offending byte
+// on the next, like:
+//
+//	Hello {^
+//	      ^
+//
+// Non-printable characters are written as \x escapes, so that they're seen
+// and don't throw off the caret.
+func (err *CompileError) Caret() string {
+	var line, caret strings.Builder
+
+	for i := 0; i < len(err.Pattern); {
+		r, size := utf8.DecodeRuneInString(err.Pattern[i:])
+
+		var width int
+
+		switch {
+		case r == '\t':
+			line.WriteByte('\t')
+
+		case r == utf8.RuneError && size == 1 || !unicode.IsPrint(r):
+			for _, b := range []byte(err.Pattern[i : i+size]) {
+				fmt.Fprintf(&line, `\x%02x`, b)
+			}
+
+			width = 4 * size
+
+		default:
+			line.WriteString(err.Pattern[i : i+size])
+			width = 1
+		}
+
+		// Tabs are kept, for the caret to line up however wide they are.
+		if i+size <= err.Offset && r == '\t' {
+			caret.WriteByte('\t')
+		} else if i+size <= err.Offset {
+			caret.WriteString(strings.Repeat(" ", width))
+		}
+
+		i += size
+	}
+
+	caret.WriteByte('^')
+
+	return line.String() + "\n" + caret.String()
+}
@@ -0,0 +1,300 @@
+package simpex_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tobiassjosten/go-simpex"
+)
+
+func TestCompileError(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		kind    simpex.ErrorKind
+		offset  int
+		error   string
+	}{
+		"invalid combination after escapes": {
+			pattern: []byte("{{{{ ** _^"),
+			kind:    simpex.ErrInvalidCombination,
+			offset:  9,
+			error:   "invalid combination at position 9",
+		},
+		"invalid combination after name": {
+			pattern: []byte("{?name:^_}"),
+			kind:    simpex.ErrInvalidCombination,
+			offset:  8,
+		},
+		"unopened capture": {
+			pattern: []byte("Lorem}"),
+			kind:    simpex.ErrUnopenedCapture,
+			offset:  5,
+		},
+		"unclosed capture": {
+			pattern: []byte("Hello {^"),
+			kind:    simpex.ErrUnclosedCapture,
+			offset:  6,
+		},
+		"nested capture": {
+			pattern: []byte("{Lorem {ipsum}}"),
+			kind:    simpex.ErrUnclosedCapture,
+			offset:  0,
+		},
+		"duplicate name": {
+			pattern: []byte("{?name:^} {?name:^}"),
+			kind:    simpex.ErrDuplicateName,
+			offset:  12,
+			error:   "duplicate capture name 'name' at position 12",
+		},
+		"unopened group": {
+			pattern: []byte("((Lorem)) ipsum)"),
+			kind:    simpex.ErrUnopenedGroup,
+			offset:  15,
+		},
+		"unclosed group": {
+			pattern: []byte("(Lorem (ipsum) dolor"),
+			kind:    simpex.ErrUnclosedGroup,
+			offset:  0,
+		},
+		"alternation outside group": {
+			pattern: []byte("Lorem|ipsum"),
+			kind:    simpex.ErrAlternationOutsideGroup,
+			offset:  5,
+		},
+		"unopened class": {
+			pattern: []byte("[a-z]]] ]"),
+			kind:    simpex.ErrUnopenedClass,
+			offset:  8,
+		},
+		"unclosed class": {
+			pattern: []byte("Lorem [ipsum"),
+			kind:    simpex.ErrUnclosedClass,
+			offset:  6,
+		},
+		"empty class": {
+			pattern: []byte("Lorem [^]"),
+			kind:    simpex.ErrEmptyClass,
+			offset:  6,
+		},
+		"invalid range": {
+			pattern: []byte("[a-z][z-a]"),
+			kind:    simpex.ErrInvalidRange,
+			offset:  7,
+		},
+		"invalid repetition": {
+			pattern: []byte("Lorem _<a>"),
+			kind:    simpex.ErrInvalidRepetition,
+			offset:  7,
+		},
+		"unclosed repetition": {
+			pattern: []byte("_<3,4"),
+			kind:    simpex.ErrUnclosedRepetition,
+			offset:  1,
+		},
+		"invalid bounds": {
+			pattern: []byte("*<5,2>"),
+			kind:    simpex.ErrInvalidBounds,
+			offset:  1,
+		},
+		"repetition too large": {
+			pattern: []byte("(_<1000>)<1000>"),
+			kind:    simpex.ErrRepetitionTooLarge,
+			offset:  9,
+		},
+		"nested repetition too large": {
+			pattern: []byte("( (_<100>)<100>)<100>"),
+			kind:    simpex.ErrRepetitionTooLarge,
+			offset:  16,
+		},
+		"capture in repetition": {
+			pattern: []byte("^<2> ({^})<2>"),
+			kind:    simpex.ErrCaptureInRepetition,
+			offset:  10,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			_, err := simpex.Compile(tc.pattern)
+
+			var cerr *simpex.CompileError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("Compile(%q) = %v, want *CompileError", tc.pattern, err)
+			}
+
+			if cerr.Kind != tc.kind || cerr.Offset != tc.offset {
+				t.Fatalf(
+					"Compile(%q) = %s at %d, want %s at %d",
+					tc.pattern, cerr.Kind, cerr.Offset, tc.kind, tc.offset,
+				)
+			}
+
+			if cerr.Pattern != string(tc.pattern) {
+				t.Fatalf("Compile(%q) error pattern %q", tc.pattern, cerr.Pattern)
+			}
+
+			if tc.error != "" && err.Error() != tc.error {
+				t.Fatalf("Compile(%q) = '%s', want '%s'", tc.pattern, err, tc.error)
+			}
+		})
+	}
+}
+
+func TestCompileErrorAs(t *testing.T) {
+	_, err := simpex.CompileSet([][]byte{[]byte("^"), []byte("{^")})
+
+	var cerr *simpex.CompileError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("CompileSet() = %v, want *CompileError", err)
+	}
+
+	if cerr.Kind != simpex.ErrUnclosedCapture {
+		t.Fatalf("CompileSet() = %s, want %s", cerr.Kind, simpex.ErrUnclosedCapture)
+	}
+}
+
+func TestCompileErrorCaret(t *testing.T) {
+	tcs := map[string]struct {
+		err   simpex.CompileError
+		caret string
+	}{
+		"ascii": {
+			err:   simpex.CompileError{Pattern: "Hello {^", Offset: 6},
+			caret: "Hello {^\n      ^",
+		},
+		"end of pattern": {
+			err:   simpex.CompileError{Pattern: "Lorem", Offset: 5},
+			caret: "Lorem\n     ^",
+		},
+		"control character": {
+			err:   simpex.CompileError{Pattern: "a\x1eb_^", Offset: 4},
+			caret: "a\\x1eb_^\n       ^",
+		},
+		"tab": {
+			err:   simpex.CompileError{Pattern: "\t{^", Offset: 1},
+			caret: "\t{^\n\t^",
+		},
+		"utf-8": {
+			err:   simpex.CompileError{Pattern: "Hej Åsa}", Offset: 8},
+			caret: "Hej Åsa}\n       ^",
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			if caret := tc.err.Caret(); caret != tc.caret {
+				t.Fatalf("Caret() =\n%s\nwant\n%s", caret, tc.caret)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type problem struct {
+		kind   simpex.ErrorKind
+		offset int
+	}
+
+	tcs := map[string]struct {
+		pattern  string
+		problems []problem
+	}{
+		"valid": {
+			pattern: "{?name:^} says, \"{*}\"",
+		},
+		"one problem": {
+			pattern:  "Hello {^",
+			problems: []problem{{simpex.ErrUnclosedCapture, 6}},
+		},
+		"every problem": {
+			pattern: "_^ \x1e {?a:^} {?a:^} [z-a] ) ^<a>",
+			problems: []problem{
+				{simpex.ErrInvalidCombination, 1},
+				{simpex.ErrDuplicateName, 14},
+				{simpex.ErrInvalidRange, 21},
+				{simpex.ErrUnopenedGroup, 25},
+				{simpex.ErrInvalidRepetition, 28},
+			},
+		},
+		"unclosed at end": {
+			pattern: "(Lorem {ipsum (dolor|",
+			problems: []problem{
+				{simpex.ErrUnclosedGroup, 0},
+				{simpex.ErrUnclosedCapture, 7},
+				{simpex.ErrUnclosedGroup, 14},
+			},
+		},
+		"problems in class": {
+			pattern: "[\x1e-a] [^] [b-a",
+			problems: []problem{
+				{simpex.ErrEmptyClass, 6},
+				{simpex.ErrUnclosedClass, 10},
+				{simpex.ErrInvalidRange, 12},
+			},
+		},
+		"unclosed capture in group": {
+			pattern: "({Lorem|ipsum})",
+			problems: []problem{
+				{simpex.ErrUnclosedCapture, 1},
+				{simpex.ErrUnopenedCapture, 13},
+			},
+		},
+		"capture in repetition": {
+			pattern: "({^})<2> {_<0>}",
+			problems: []problem{
+				{simpex.ErrCaptureInRepetition, 5},
+				{simpex.ErrInvalidBounds, 11},
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			var problems []problem
+			for _, err := range simpex.ValidateString(tc.pattern) {
+				if err.Pattern != tc.pattern {
+					t.Fatalf("ValidateString(%q) error pattern %q", tc.pattern, err.Pattern)
+				}
+
+				problems = append(problems, problem{err.Kind, err.Offset})
+			}
+
+			if !reflect.DeepEqual(tc.problems, problems) {
+				t.Fatalf("ValidateString(%q) = %v, want %v", tc.pattern, problems, tc.problems)
+			}
+		})
+	}
+}
+
+// FuzzValidate verifies that Validate() finds problems with exactly the
+// patterns that don't compile, including the one Compile() stops at.
+func FuzzValidate(f *testing.F) {
+	f.Add([]byte("_^ \x1e {?a:^} {?a:^} [z-a] ) ^<a>"))
+	f.Add([]byte("(Lorem {ipsum (dolor|"))
+	f.Add([]byte("({^})<2> [^] *<5,2>"))
+
+	f.Fuzz(func(t *testing.T, pattern []byte) {
+		errs := simpex.Validate(pattern)
+
+		_, err := simpex.Compile(pattern)
+		if err == nil && errs != nil {
+			t.Fatalf("Validate(%q) = %v, want nil", pattern, errs)
+		} else if err == nil {
+			return
+		}
+
+		var cerr *simpex.CompileError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("Compile(%q) = %v, want *CompileError", pattern, err)
+		}
+
+		for _, e := range errs {
+			if e.Kind == cerr.Kind && e.Offset == cerr.Offset {
+				return
+			}
+		}
+
+		t.Fatalf("Validate(%q) = %v, missing %v", pattern, errs, cerr)
+	})
+}
@@ -0,0 +1,86 @@
+package simpex
+
+// programSymbols are the special characters tests expect for symbols in programs.
+var programSymbols = map[op]byte{
+	opSpace:        1,
+	opCaptureStart: 2,
+	opCaptureEnd:   3,
+	opGroupStart:   4,
+	opAlternation:  5,
+	opGroupEnd:     6,
+	opClass:        7,
+	opNumber:       28,
+	opPhrase:       29,
+	opWord:         30,
+	opChar:         31,
+}
+
+// Instruction exposes an instruction of a Simpex to tests, as either the text
+// it matches literally or the special character of its symbol.
+type Instruction struct {
+	Literal []byte
+	Symbol  byte
+}
+
+// Instructions exposes the compiled form of a Simpex to tests, as its
+// instructions in order.
+func Instructions(sx Simpex) []Instruction {
+	instructions := []Instruction{}
+	for _, in := range sx.insts {
+		if in.op == opLiteral {
+			instructions = append(instructions, Instruction{Literal: in.literal})
+		} else {
+			instructions = append(instructions, Instruction{Symbol: programSymbols[in.op]})
+		}
+	}
+
+	return instructions
+}
+
+// Program exposes the compiled form of a Simpex to tests, as a program with
+// symbols as special characters among the literal text. Literal text can
+// contain those same characters, so see Instructions() to tell them apart.
+func Program(sx Simpex) []byte {
+	program := []byte{}
+	for _, in := range Instructions(sx) {
+		if in.Literal != nil {
+			program = append(program, in.Literal...)
+		} else {
+			program = append(program, in.Symbol)
+		}
+	}
+
+	return program
+}
+
+// Options exposes the options a Simpex was compiled with to tests.
+func Options(sx Simpex) CompileOptions {
+	return sx.opts
+}
+
+// Class exposes the character class of the nth class symbol of a Simpex to
+// tests, as whether it's negated and its ranges.
+func Class(sx Simpex, n int) (bool, []rune) {
+	c := sx.symbol(opClass, n).class
+	return c.negated, c.ranges
+}
+
+// Phrase exposes the nth phrase symbol of a Simpex to tests, as whether it's
+// greedy and its lowest and highest number of characters.
+func Phrase(sx Simpex, n int) (bool, int, int) {
+	p := sx.symbol(opPhrase, n).phrase
+	return p.greedy, p.low, p.high
+}
+
+// symbol returns the nth instruction of a Simpex doing o.
+func (sx Simpex) symbol(o op, n int) inst {
+	for _, in := range sx.insts {
+		if in.op == o && n == 0 {
+			return in
+		} else if in.op == o {
+			n--
+		}
+	}
+
+	panic("no such symbol")
+}
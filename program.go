@@ -0,0 +1,90 @@
+package simpex
+
+// op is what an instruction of a compiled pattern does.
+type op uint8
+
+const (
+	opLiteral op = iota
+	opCaptureStart
+	opCaptureEnd
+	opGroupStart
+	opAlternation
+	opGroupEnd
+	opChar
+	opWord
+	opNumber
+	opPhrase
+	opSpace
+	opClass
+)
+
+// inst is an instruction of a compiled pattern, being either a run of literal
+// text or a single symbol.
+type inst struct {
+	op op
+
+	// The text matched by literals.
+	literal []byte
+
+	// For captures, the number of the capture. For the start of a group or
+	// a separator of its branches, the instruction of the following
+	// separator or end.
+	arg int
+
+	// For phrases, the first instruction following them that isn't part of
+	// a capture, or -1 if there's nothing but captures left. Where it's
+	// literal text or a space, only their occurrences can end the phrase.
+	next int
+
+	// For the start of a group, the numbers of the first capture in it and
+	// of the first one following it, so that those of branches not taken
+	// can be reset without going through them.
+	captures [2]int
+
+	// The characters matched by classes.
+	class *class
+
+	// How much text phrases match, which copies of them share.
+	phrase *phrase
+}
+
+// link the symbols of instructions as described by inst, once they're all in
+// place.
+func link(insts []inst) {
+	// Instructions of the start and of the latest start or separator of
+	// each open group.
+	var starts, groups []int
+
+	captures := 0
+
+	for pc := range insts {
+		switch insts[pc].op {
+		case opCaptureStart:
+			captures++
+
+		case opGroupStart:
+			insts[pc].captures[0] = captures
+			starts = append(starts, pc)
+			groups = append(groups, pc)
+
+		case opAlternation:
+			insts[groups[len(groups)-1]].arg = pc
+			groups[len(groups)-1] = pc
+
+		case opGroupEnd:
+			insts[starts[len(starts)-1]].captures[1] = captures
+			insts[groups[len(groups)-1]].arg = pc
+			starts = starts[:len(starts)-1]
+			groups = groups[:len(groups)-1]
+
+		case opPhrase:
+			insts[pc].next = -1
+			for next := pc + 1; next < len(insts); next++ {
+				if o := insts[next].op; o != opCaptureStart && o != opCaptureEnd {
+					insts[pc].next = next
+					break
+				}
+			}
+		}
+	}
+}
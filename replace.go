@@ -0,0 +1,116 @@
+package simpex
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Replace every occurrence of a pattern in a text with a template, expanded
+// like with Expand(). If the pattern matches, the rewritten text is returned.
+// If it doesn't, nil is returned.
+func (sx Simpex) Replace(text, template []byte) []byte {
+	all := sx.FindAllIndex(text, -1)
+	if all == nil {
+		return nil
+	}
+
+	replaced := []byte{}
+	last := 0
+
+	for _, indexes := range all {
+		replaced = append(replaced, text[last:indexes[0]]...)
+		replaced = sx.expand(replaced, template, text, indexes)
+		last = indexes[1]
+	}
+
+	return append(replaced, text[last:]...)
+}
+
+// Expand a template with the captures of matching a text against a pattern,
+// appending it to dst. If the pattern matches, the extended dst is returned.
+// If it doesn't, nil is returned.
+//
+// Like with regexp.Expand(), captures are referenced in the template by $1 or
+// ${1} for the first capture and $name or ${name} for named ones, while $0
+// references the whole match. References to captures that don't exist are
+// replaced by empty strings. A literal $ is written as $$.
+func (sx Simpex) Expand(dst, template, text []byte) []byte {
+	indexes := sx.MatchIndex(text)
+	if indexes == nil {
+		return nil
+	}
+
+	return sx.expand(dst, template, text, indexes)
+}
+
+// expand appends a template to dst, with references to captures replaced by
+// the spans of text given by indexes.
+func (sx Simpex) expand(dst, template, text []byte, indexes []int) []byte {
+	for {
+		i := bytes.IndexByte(template, '$')
+		if i < 0 {
+			break
+		}
+
+		dst = append(dst, template[:i]...)
+		template = template[i+1:]
+
+		if len(template) > 0 && template[0] == '$' {
+			dst = append(dst, '$')
+			template = template[1:]
+			continue
+		}
+
+		name, rest, ok := reference(template)
+		if !ok {
+			// Malformed references are left as they are.
+			dst = append(dst, '$')
+			continue
+		}
+
+		template = rest
+
+		index, err := strconv.Atoi(name)
+		if err != nil {
+			if index = sx.subexpIndex(name); index >= 0 {
+				index++
+			}
+		}
+
+		if index >= 0 && index*2 < len(indexes) && indexes[index*2] >= 0 {
+			dst = append(dst, text[indexes[index*2]:indexes[index*2+1]]...)
+		}
+	}
+
+	return append(dst, template...)
+}
+
+// reference extracts the name or number of a capture, from a template right
+// after a $, returning it along with the rest of the template.
+func reference(template []byte) (string, []byte, bool) {
+	braced := len(template) > 0 && template[0] == '{'
+	if braced {
+		template = template[1:]
+	}
+
+	end := 0
+	for end < len(template) && (isalphanum(rune(template[end])) || template[end] == '_') {
+		end++
+	}
+
+	if end == 0 {
+		return "", nil, false
+	}
+
+	name, rest := string(template[:end]), template[end:]
+
+	if braced {
+		if len(rest) == 0 || rest[0] != '}' {
+			return "", nil, false
+		}
+
+		rest = rest[1:]
+	}
+
+	return name, rest, true
+}
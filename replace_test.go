@@ -0,0 +1,174 @@
+package simpex_test
+
+import (
+	"testing"
+
+	"github.com/tobiassjosten/go-simpex"
+)
+
+func TestReplace(t *testing.T) {
+	tcs := map[string]struct {
+		pattern  []byte
+		opts     simpex.CompileOptions
+		text     []byte
+		template []byte
+		replaced []byte
+	}{
+		"mismatch": {
+			pattern:  []byte("Lorem {^}"),
+			text:     []byte("dolor sit amet."),
+			template: []byte("$1"),
+		},
+
+		"literal": {
+			pattern:  []byte("ipsum"),
+			text:     []byte("Lorem ipsum dolor."),
+			template: []byte("IPSUM"),
+			replaced: []byte("Lorem IPSUM dolor."),
+		},
+		"every occurrence": {
+			pattern:  []byte("{^}m"),
+			text:     []byte("Lorem ipsum dolor sit amet."),
+			template: []byte("<$1>"),
+			replaced: []byte("<Lore> <ipsu> dolor sit <a>et."),
+		},
+		"whole match": {
+			pattern:  []byte("d_l_r"),
+			text:     []byte("Lorem ipsum dolor."),
+			template: []byte("[$0]"),
+			replaced: []byte("Lorem ipsum [dolor]."),
+		},
+		"empty template": {
+			pattern:  []byte(" {^}"),
+			text:     []byte("Lorem ipsum dolor."),
+			template: []byte(""),
+			replaced: []byte("Lorem."),
+		},
+		"empty occurrences between runes": {
+			pattern:  []byte("(x)?"),
+			opts:     simpex.CompileOptions{UTF8: true},
+			text:     []byte("åä"),
+			template: []byte("-"),
+			replaced: []byte("-å-ä-"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := tc.opts.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			replaced := sx.Replace(tc.text, tc.template)
+
+			if (tc.replaced == nil) != (replaced == nil) || string(tc.replaced) != string(replaced) {
+				t.Fatalf(
+					"Replace(%q, %q) = %q, want %q",
+					tc.text, tc.template, replaced, tc.replaced,
+				)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tcs := map[string]struct {
+		pattern  []byte
+		text     []byte
+		template []byte
+		expanded []byte
+	}{
+		"mismatch": {
+			pattern:  []byte("Lorem {^}"),
+			text:     []byte("Lorem ipsum dolor."),
+			template: []byte("$1"),
+		},
+
+		"no references": {
+			pattern:  []byte("Lorem {^}"),
+			text:     []byte("Lorem ipsum"),
+			template: []byte("dolor"),
+			expanded: []byte("dolor"),
+		},
+		"numbered": {
+			pattern:  []byte("{^} {^} {*}"),
+			text:     []byte("Lorem ipsum dolor sit amet."),
+			template: []byte("$3, $2 $1"),
+			expanded: []byte("dolor sit amet., ipsum Lorem"),
+		},
+		"braced numbered": {
+			pattern:  []byte("{^} {^}"),
+			text:     []byte("Lorem ipsum"),
+			template: []byte("${2}${1}0"),
+			expanded: []byte("ipsumLorem0"),
+		},
+		"whole match": {
+			pattern:  []byte("Lorem {^}"),
+			text:     []byte("Lorem ipsum"),
+			template: []byte("<$0>"),
+			expanded: []byte("<Lorem ipsum>"),
+		},
+		"named": {
+			pattern:  []byte("{?first:^} {?last:^}"),
+			text:     []byte("Lorem ipsum"),
+			template: []byte("$last ${first}!"),
+			expanded: []byte("ipsum Lorem!"),
+		},
+		"longest name": {
+			pattern:  []byte("{?a:^} {?ab:^}"),
+			text:     []byte("Lorem ipsum"),
+			template: []byte("$ab_ $ab ${a}b"),
+			expanded: []byte(" ipsum Loremb"),
+		},
+		"unknown references": {
+			pattern:  []byte("{?first:^}"),
+			text:     []byte("Lorem"),
+			template: []byte("[$2][$last][${9}][$1x]"),
+			expanded: []byte("[][][][]"),
+		},
+		"escaped dollar": {
+			pattern:  []byte("{^}"),
+			text:     []byte("100"),
+			template: []byte("$$$1"),
+			expanded: []byte("$100"),
+		},
+		"malformed references": {
+			pattern:  []byte("{^}"),
+			text:     []byte("Lorem"),
+			template: []byte("$ ${1 ${} $"),
+			expanded: []byte("$ ${1 ${} $"),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			expanded := sx.Expand(nil, tc.template, tc.text)
+
+			if (tc.expanded == nil) != (expanded == nil) || string(tc.expanded) != string(expanded) {
+				t.Fatalf(
+					"Expand(nil, %q, %q) = %q, want %q",
+					tc.template, tc.text, expanded, tc.expanded,
+				)
+			}
+		})
+	}
+}
+
+func TestExpandAppends(t *testing.T) {
+	sx, err := simpex.Compile([]byte("{^}"))
+	if err != nil {
+		t.Fatalf("Compile(\"{^}\") unexpected error '%s'", err)
+	}
+
+	dst := []byte("Lorem ")
+
+	if expanded := sx.Expand(dst, []byte("$1"), []byte("ipsum")); string(expanded) != "Lorem ipsum" {
+		t.Fatalf("Expand(%q, \"$1\", \"ipsum\") = %q, want \"Lorem ipsum\"", dst, expanded)
+	}
+}
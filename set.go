@@ -0,0 +1,294 @@
+package simpex
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Set is a collection of patterns, indexed by their literal text so that a
+// text can be matched against thousands of them without trying each one in
+// turn. It's safe for concurrent use.
+type Set struct {
+	patterns []Simpex
+
+	// Patterns are indexed by the longest literal text they contain,
+	// which any matching text must contain as well. Case-insensitive ones
+	// have theirs lowercased, to be found in lowercased texts. Those
+	// without any are tried for every text.
+	literals automaton
+	folded   automaton
+	rest     []int
+}
+
+// SetMatch is a pattern of a Set that matched a text.
+type SetMatch struct {
+	// ID is the index of the pattern in the Set.
+	ID int
+
+	// Captures are the same as returned by Simpex.Match().
+	Captures [][]byte
+}
+
+// NewSet creates a Set of compiled patterns, identified by their indexes.
+func NewSet(patterns []Simpex) *Set {
+	set := &Set{patterns: patterns}
+
+	for id, sx := range patterns {
+		literal := sx.literal()
+
+		switch {
+		case len(literal) == 0:
+			set.rest = append(set.rest, id)
+
+		case sx.opts.CaseInsensitive:
+			folded := make([]byte, len(literal))
+			for i, char := range literal {
+				folded[i] = lower(char)
+			}
+
+			set.folded.insert(folded, id)
+
+		default:
+			set.literals.insert(literal, id)
+		}
+	}
+
+	set.literals.link()
+	set.folded.link()
+
+	return set
+}
+
+// CompileSet compiles patterns and creates a Set of them. This is a
+// convenience wrapper for Compile() and NewSet().
+func CompileSet(patterns [][]byte) (*Set, error) {
+	compiled := make([]Simpex, len(patterns))
+
+	for i, pattern := range patterns {
+		sx, err := Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", i, err)
+		}
+
+		compiled[i] = sx
+	}
+
+	return NewSet(compiled), nil
+}
+
+// Match a text against the patterns of the Set, returning those that match in
+// order of their IDs. If none do, nil is returned.
+func (set *Set) Match(text []byte) []SetMatch {
+	// Patterns already among the candidates, by ID.
+	seen := make([]uint64, (len(set.patterns)+63)/64)
+
+	candidates := append([]int{}, set.rest...)
+	candidates = set.literals.search(candidates, seen, text, false)
+	candidates = set.folded.search(candidates, seen, text, true)
+
+	sort.Ints(candidates)
+
+	var matches []SetMatch
+
+	for _, id := range candidates {
+		if captures := set.patterns[id].Match(text); captures != nil {
+			matches = append(matches, SetMatch{ID: id, Captures: captures})
+		}
+	}
+
+	return matches
+}
+
+// literal returns the longest literal text of the pattern, which any matching
+// text must contain. Captures are seen through, since they consume no text,
+// while groups are skipped, since texts need only contain one of their
+// branches.
+//
+// Case-insensitive patterns in UTF-8 mode can have their ASCII letters matched
+// by other runes, like 'k' by the Kelvin sign. So only ASCII characters folding
+// exclusively into other ASCII characters are considered part of literals.
+func (sx Simpex) literal() []byte {
+	var longest, current []byte
+
+	unsafe := sx.opts.CaseInsensitive && sx.opts.UTF8
+
+	depth := 0
+
+	for _, in := range sx.insts {
+		switch in.op {
+		case opGroupStart:
+			depth++
+		case opGroupEnd:
+			depth--
+		}
+
+		if in.op == opCaptureStart || in.op == opCaptureEnd {
+			continue
+		}
+
+		if depth > 0 || in.op != opLiteral {
+			current = nil
+			continue
+		}
+
+		for _, char := range in.literal {
+			if unsafe && !isfoldsafe(char) {
+				current = nil
+				continue
+			}
+
+			current = append(current, char)
+			if len(current) > len(longest) {
+				longest = current
+			}
+		}
+	}
+
+	return longest
+}
+
+// isfoldsafe tells whether a byte is an ASCII character that only folds into
+// other ASCII characters.
+func isfoldsafe(char byte) bool {
+	return char < utf8.RuneSelf && lower(char) != 'k' && lower(char) != 's'
+}
+
+// automaton is an Aho–Corasick automaton, finding which of many literals a
+// text contains in a single pass over it.
+type automaton struct {
+	nodes []acnode
+}
+
+type acnode struct {
+	children map[byte]int
+
+	// Where to continue when no child matches the next byte, being the
+	// longest proper suffix of this node that is also in the automaton.
+	fail int
+
+	// The nearest node along the chain of failures that ends a literal,
+	// or zero if there is none.
+	output int
+
+	// IDs of the patterns whose literal ends at this node.
+	ids []int
+}
+
+func (a *automaton) insert(literal []byte, id int) {
+	if len(a.nodes) == 0 {
+		a.nodes = []acnode{{}}
+	}
+
+	node := 0
+
+	for _, char := range literal {
+		if a.nodes[node].children == nil {
+			a.nodes[node].children = map[byte]int{}
+		}
+
+		child, ok := a.nodes[node].children[char]
+		if !ok {
+			child = len(a.nodes)
+			a.nodes = append(a.nodes, acnode{})
+			a.nodes[node].children[char] = child
+		}
+
+		node = child
+	}
+
+	a.nodes[node].ids = append(a.nodes[node].ids, id)
+}
+
+// link computes failure and output links, breadth first so that those of
+// shorter suffixes are always known first.
+func (a *automaton) link() {
+	if len(a.nodes) == 0 {
+		return
+	}
+
+	queue := []int{}
+	for _, child := range a.nodes[0].children {
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for char, child := range a.nodes[node].children {
+			queue = append(queue, child)
+
+			fail := a.nodes[node].fail
+			for fail > 0 && a.next(fail, char) < 0 {
+				fail = a.nodes[fail].fail
+			}
+
+			if next := a.next(fail, char); next >= 0 && next != child {
+				fail = next
+			}
+
+			a.nodes[child].fail = fail
+
+			if len(a.nodes[fail].ids) > 0 {
+				a.nodes[child].output = fail
+			} else {
+				a.nodes[child].output = a.nodes[fail].output
+			}
+		}
+	}
+}
+
+func (a *automaton) next(node int, char byte) int {
+	if child, ok := a.nodes[node].children[char]; ok {
+		return child
+	}
+
+	return -1
+}
+
+// search appends to ids the IDs of every literal found in text, optionally
+// lowercasing the text as it goes. IDs are only appended if not yet seen, and
+// then marked as such.
+func (a *automaton) search(ids []int, seen []uint64, text []byte, fold bool) []int {
+	if len(a.nodes) == 0 {
+		return ids
+	}
+
+	node := 0
+
+	for _, char := range text {
+		if fold {
+			char = lower(char)
+		}
+
+		for node > 0 && a.next(node, char) < 0 {
+			node = a.nodes[node].fail
+		}
+
+		if next := a.next(node, char); next >= 0 {
+			node = next
+		}
+
+		for output := node; output > 0; output = a.nodes[output].output {
+			found := a.nodes[output].ids
+			if len(found) == 0 {
+				continue
+			}
+
+			// Each pattern has a single literal, so once the IDs of a
+			// node are seen, so are those of the rest of its chain.
+			if seen[found[0]/64]&(1<<(found[0]%64)) != 0 {
+				break
+			}
+
+			for _, id := range found {
+				seen[id/64] |= 1 << (id % 64)
+			}
+
+			ids = append(ids, found...)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,220 @@
+package simpex_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/tobiassjosten/go-simpex"
+)
+
+func TestSet(t *testing.T) {
+	set, err := simpex.CompileSet([][]byte{
+		[]byte("Lorem ipsum dolor sit amet."),
+		[]byte("Lorem {^} dolor sit amet."),
+		[]byte("{*} dolor sit amet."),
+		[]byte("{*} dolor {*}"),
+		[]byte("{Lorem} ipsum {*}"),
+		[]byte("{*}"),
+		[]byte("Lorem"),
+		[]byte("amet."),
+		[]byte(""),
+	})
+	if err != nil {
+		t.Fatalf("CompileSet() unexpected error '%s'", err)
+	}
+
+	tcs := map[string]struct {
+		text    []byte
+		matches []simpex.SetMatch
+	}{
+		"match many": {
+			text: []byte("Lorem ipsum dolor sit amet."),
+			matches: []simpex.SetMatch{
+				{ID: 0, Captures: [][]byte{}},
+				{ID: 1, Captures: [][]byte{[]byte("ipsum")}},
+				{ID: 2, Captures: [][]byte{[]byte("Lorem ipsum")}},
+				{ID: 3, Captures: [][]byte{
+					[]byte("Lorem ipsum"),
+					[]byte("sit amet."),
+				}},
+				{ID: 4, Captures: [][]byte{
+					[]byte("Lorem"),
+					[]byte("dolor sit amet."),
+				}},
+				{ID: 5, Captures: [][]byte{
+					[]byte("Lorem ipsum dolor sit amet."),
+				}},
+			},
+		},
+		"match prefix": {
+			text: []byte("Lorem"),
+			matches: []simpex.SetMatch{
+				{ID: 5, Captures: [][]byte{[]byte("Lorem")}},
+				{ID: 6, Captures: [][]byte{}},
+			},
+		},
+		"match suffix": {
+			text: []byte("amet."),
+			matches: []simpex.SetMatch{
+				{ID: 5, Captures: [][]byte{[]byte("amet.")}},
+				{ID: 7, Captures: [][]byte{}},
+			},
+		},
+		"match empty": {
+			text: []byte(""),
+			matches: []simpex.SetMatch{
+				{ID: 8, Captures: [][]byte{}},
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			matches := set.Match(tc.text)
+
+			if !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"Match(%q)\ngot  %q\nwant %q",
+					tc.text, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+func TestSetOverlappingLiterals(t *testing.T) {
+	set, err := simpex.CompileSet([][]byte{
+		[]byte("{*}he{*}"),
+		[]byte("{*}she{*}"),
+		[]byte("{*}his{*}"),
+		[]byte("{*}hers"),
+		[]byte("u{*}"),
+	})
+	if err != nil {
+		t.Fatalf("CompileSet() unexpected error '%s'", err)
+	}
+
+	want := []simpex.SetMatch{
+		{ID: 0, Captures: [][]byte{[]byte("us"), []byte("rs")}},
+		{ID: 1, Captures: [][]byte{[]byte("u"), []byte("rs")}},
+		{ID: 3, Captures: [][]byte{[]byte("us")}},
+		{ID: 4, Captures: [][]byte{[]byte("shers")}},
+	}
+
+	if matches := set.Match([]byte("ushers")); !reflect.DeepEqual(want, matches) {
+		t.Fatalf("Match(\"ushers\")\ngot  %q\nwant %q", matches, want)
+	}
+}
+
+func TestSetRepeatedLiterals(t *testing.T) {
+	set, err := simpex.CompileSet([][]byte{
+		[]byte("{*}a{*}"),
+		[]byte("{*}aa{*}"),
+		[]byte("{*}ba{*}"),
+	})
+	if err != nil {
+		t.Fatalf("CompileSet() unexpected error '%s'", err)
+	}
+
+	want := []simpex.SetMatch{
+		{ID: 0, Captures: [][]byte{[]byte("b"), []byte("abaa")}},
+		{ID: 1, Captures: [][]byte{[]byte("b"), []byte("baa")}},
+		{ID: 2, Captures: [][]byte{[]byte("baa"), []byte("a")}},
+	}
+
+	if matches := set.Match([]byte("baabaa")); !reflect.DeepEqual(want, matches) {
+		t.Fatalf("Match(\"baabaa\")\ngot  %q\nwant %q", matches, want)
+	}
+
+	var patterns [][]byte
+	for i := 0; i < 3000; i++ {
+		patterns = append(patterns, []byte("{*} {*}"))
+	}
+
+	set, err = simpex.CompileSet(patterns)
+	if err != nil {
+		t.Fatalf("CompileSet() unexpected error '%s'", err)
+	}
+
+	if matches := set.Match(bytes.Repeat([]byte(" "), 500)); len(matches) != len(patterns) {
+		t.Fatalf("Match() = %d matches, want %d", len(matches), len(patterns))
+	}
+}
+
+func TestSetAlternation(t *testing.T) {
+	set, err := simpex.CompileSet([][]byte{
+		[]byte("You (hit|miss) the {^}."),
+		[]byte("(hit|miss)"),
+	})
+	if err != nil {
+		t.Fatalf("CompileSet() unexpected error '%s'", err)
+	}
+
+	want := []simpex.SetMatch{{ID: 0, Captures: [][]byte{[]byte("rat")}}}
+	if matches := set.Match([]byte("You miss the rat.")); !reflect.DeepEqual(want, matches) {
+		t.Fatalf("Match(\"You miss the rat.\")\ngot  %q\nwant %q", matches, want)
+	}
+
+	want = []simpex.SetMatch{{ID: 1, Captures: [][]byte{}}}
+	if matches := set.Match([]byte("miss")); !reflect.DeepEqual(want, matches) {
+		t.Fatalf("Match(\"miss\")\ngot  %q\nwant %q", matches, want)
+	}
+}
+
+func TestSetCaseInsensitive(t *testing.T) {
+	var sxs []simpex.Simpex
+
+	for _, tc := range []struct {
+		pattern string
+		opts    simpex.CompileOptions
+	}{
+		{pattern: "LOREM {^}"},
+		{pattern: "LOREM {^}", opts: simpex.CompileOptions{CaseInsensitive: true}},
+		{pattern: "{*}K", opts: simpex.CompileOptions{CaseInsensitive: true}},
+		{pattern: "{*}K", opts: simpex.CompileOptions{CaseInsensitive: true, UTF8: true}},
+	} {
+		sx, err := tc.opts.Compile([]byte(tc.pattern))
+		if err != nil {
+			t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+		}
+
+		sxs = append(sxs, sx)
+	}
+
+	set := simpex.NewSet(sxs)
+
+	want := []simpex.SetMatch{{ID: 1, Captures: [][]byte{[]byte("ipsum")}}}
+	if matches := set.Match([]byte("Lorem ipsum")); !reflect.DeepEqual(want, matches) {
+		t.Fatalf("Match(\"Lorem ipsum\")\ngot  %q\nwant %q", matches, want)
+	}
+
+	want = []simpex.SetMatch{{ID: 3, Captures: [][]byte{[]byte("300")}}}
+	if matches := set.Match([]byte("300\u212a")); !reflect.DeepEqual(want, matches) {
+		t.Fatalf("Match(\"300\\u212a\")\ngot  %q\nwant %q", matches, want)
+	}
+}
+
+func TestSetMismatch(t *testing.T) {
+	set, err := simpex.CompileSet([][]byte{
+		[]byte("Lorem {^}"),
+		[]byte("{^} amet."),
+	})
+	if err != nil {
+		t.Fatalf("CompileSet() unexpected error '%s'", err)
+	}
+
+	if matches := set.Match([]byte("dolor sit")); matches != nil {
+		t.Fatalf("Match() = %q, want nil", matches)
+	}
+}
+
+func TestCompileSetError(t *testing.T) {
+	_, err := simpex.CompileSet([][]byte{
+		[]byte("Lorem {^}"),
+		[]byte("{^ amet."),
+	})
+	if err == nil {
+		t.Fatalf("CompileSet() missing error")
+	}
+}
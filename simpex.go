@@ -7,28 +7,26 @@ package simpex
 
 import (
 	"bytes"
-	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-const (
-	// These special symbols makes compilation and pattern matching a lot
-	// easier and faster later on.
-	captureStart byte = 2
-	captureEnd   byte = 3
-	phraseMatch  byte = 29
-	wordMatch    byte = 30
-	charMatch    byte = 31
-)
-
-var (
-	matchchars = map[byte]byte{
-		'{': captureStart,
-		'}': captureEnd,
-		'_': charMatch,
-		'^': wordMatch,
-		'*': phraseMatch,
-	}
-)
+// symbols are the instructions of the characters with special meaning in
+// patterns.
+var symbols = map[byte]op{
+	'{': opCaptureStart,
+	'}': opCaptureEnd,
+	'(': opGroupStart,
+	'|': opAlternation,
+	')': opGroupEnd,
+	'_': opChar,
+	'^': opWord,
+	'*': opPhrase,
+	'#': opNumber,
+	'~': opSpace,
+	'[': opClass,
+}
 
 // Match a text against a pattern to see if it matches. This is a convenience
 // wrapper for Compile() and Simpex.Match(). If it matches, captures matches
@@ -42,240 +40,1570 @@ func Match(pattern []byte, text []byte) ([][]byte, error) {
 	return sx.Match(text), nil
 }
 
-// Simpex represents a compiled simple expression. It is assumed to be a valid
-// pattern, so any construction outside of Compile() is done at one's own risk.
-type Simpex []byte
+// Simpex represents a compiled simple expression, as returned by Compile().
+type Simpex struct {
+	// The instructions of the pattern, with literal text and symbols
+	// parsed apart.
+	insts []inst
+
+	// Names of captures, in order, with empty strings for unnamed ones.
+	names []string
+
+	opts CompileOptions
+}
+
+// CompileOptions changes how patterns are compiled and matched.
+type CompileOptions struct {
+	// UTF8 treats texts as UTF-8 encoded, so that _ matches one rune rather
+	// than one byte and ^ matches letters and digits of any script rather
+	// than only ASCII ones.
+	UTF8 bool
+
+	// CaseInsensitive makes literal text in patterns match regardless of
+	// case, while captures keep the case of the text. In UTF-8 mode, case
+	// is folded for all of Unicode, and otherwise only for ASCII letters.
+	CaseInsensitive bool
+
+	// LooseSpaces makes every run of literal spaces in patterns match like
+	// the ~ symbol, so that any amount of spaces and tabs will do.
+	LooseSpaces bool
+
+	// WordChars are characters matched by ^ in addition to letters and
+	// digits, like "-'" for names such as Jean-Luc and o'Neil. Without
+	// UTF-8 mode, they're taken as bytes rather than runes.
+	WordChars string
+
+	// IsWord replaces the letters and digits matched by ^ with whatever
+	// characters it reports as part of words, still along with WordChars.
+	// Without UTF-8 mode, it's given single bytes.
+	IsWord func(r rune) bool
+}
 
 // Compile validates and converts a given pattern into something optimized for
 // matching.
 func Compile(pattern []byte) (Simpex, error) {
+	return CompileOptions{}.Compile(pattern)
+}
+
+// Compile is like the global Compile(), but with the given options.
+func (opts CompileOptions) Compile(pattern []byte) (Simpex, error) {
+	sx, errs := opts.compile(pattern, false)
+	if errs != nil {
+		errs[0].Pattern = string(pattern)
+		return Simpex{}, errs[0]
+	}
+
+	return sx, nil
+}
+
+// compile does the work of Compile() and Validate(), returning errors without
+// the pattern. Unless all errors are wanted, it stops at the first one.
+// Otherwise it recovers from each as best it can and carries on, though what
+// it compiles is then of no use.
+func (opts CompileOptions) compile(pattern []byte, all bool) (Simpex, []*CompileError) {
+	var errs []*CompileError
+
+	// fail records an error, telling whether to stop there.
+	fail := func(err *CompileError) bool {
+		errs = append(errs, err)
+		return !all
+	}
+
 	capturing := false
 
-	// Avoid mutating pattern slice.
-	compiled := make([]byte, len(pattern))
-	copy(compiled, pattern)
+	// Offsets in the pattern of the current capture and of open groups,
+	// for errors about them not being closed.
+	var captureOffset int
+	var groups []int
+
+	// How deeply nested groups are where the current capture started.
+	// Captures and groups must not overlap.
+	captureDepth := 0
+
+	names := []string{}
+
+	var insts []inst
+
+	// last returns what the latest instruction does, with literal text
+	// for none at all.
+	last := func() op {
+		if len(insts) == 0 {
+			return opLiteral
+		}
+
+		return insts[len(insts)-1].op
+	}
+
+	// literal adds literal text, joined to any right before.
+	literal := func(text []byte) {
+		if len(text) == 0 {
+			return
+		}
+
+		if last() == opLiteral && len(insts) > 0 {
+			insts[len(insts)-1].literal = append(insts[len(insts)-1].literal, text...)
+			return
+		}
+
+		insts = append(insts, inst{op: opLiteral, literal: append([]byte{}, text...)})
+	}
 
 	uncombinable := false
 
-	for i := 0; i < len(compiled); i++ {
-		char := compiled[i]
+	// How many instructions there were right after the latest
+	// repetition, since neither '?' nor '<' apply to what it was expanded
+	// into.
+	repeated := -1
 
-		switch char {
-		case captureStart, captureEnd, charMatch, wordMatch, phraseMatch:
-			return nil, fmt.Errorf(
-				"reserved character '%x' at position %d",
-				char, i,
-			)
+	// Where the doubled colon escaping the latest would-be capture name
+	// is, which is then the literal text of a single colon.
+	colon := -1
 
+	for i := 0; i < len(pattern); i++ {
+		char := pattern[i]
+
+		switch char {
 		// These two are only here for all non-symbolic characters to
 		// fall under the default case. Their logic follows after the
 		// switch (except for the non-capture, uncombinable stuff).
 		case '{', '}':
-		case '_', '^', '*':
-			if uncombinable {
-				return nil, fmt.Errorf("invalid combination at position %d", i)
+		case '(', '|', ')', '~', ']':
+			uncombinable = false
+
+		case '[':
+			uncombinable = false
+
+			repeat := run(pattern, i)
+
+			// For '[' we want the matching symbol after, since what
+			// follows it is the class rather than more pattern.
+			literal(bytes.Repeat([]byte{char}, repeat/2))
+			size := 0
+
+			if repeat%2 != 0 {
+				c, n, classErrs := parseclass(pattern[i+repeat:], i+repeat, opts.UTF8)
+				for _, err := range classErrs {
+					if fail(err) {
+						return Simpex{}, errs
+					}
+				}
+
+				insts = append(insts, inst{op: opClass, class: &c})
+				size = n
+			}
+
+			i += repeat + size - 1
+
+			continue
+
+		case ' ':
+			uncombinable = false
+
+			if !opts.LooseSpaces {
+				literal([]byte{char})
+				continue
+			}
+
+			insts = append(insts, inst{op: opSpace})
+			i += run(pattern, i) - 1
+
+			continue
+
+		// Following the end of a group, it makes the group optional.
+		case '?':
+			if last() != opGroupEnd || len(insts) == repeated {
+				uncombinable = false
+				literal([]byte{char})
+				continue
+			}
+
+			repeat := run(pattern, i)
+
+			// Optional groups get an empty last branch, by turning their
+			// end into a separator followed by a new end.
+			if repeat%2 != 0 {
+				insts[len(insts)-1].op = opAlternation
+				insts = append(insts, inst{op: opGroupEnd})
+			}
+
+			literal(bytes.Repeat([]byte{char}, repeat/2))
+			i += repeat - 1
+
+			continue
+
+		// Following a phrase, it makes the phrase greedy.
+		case '+':
+			if last() != opPhrase || len(insts) == repeated {
+				uncombinable = false
+				literal([]byte{char})
+				continue
+			}
+
+			repeat := run(pattern, i)
+
+			if repeat%2 != 0 {
+				insts[len(insts)-1].phrase.greedy = true
+			}
+
+			// Phrases stay uncombinable, unless followed by escaped '+'.
+			uncombinable = uncombinable && repeat == 1
+
+			literal(bytes.Repeat([]byte{char}, repeat/2))
+			i += repeat - 1
+
+			continue
+
+		// Following a phrase, it bounds the length of the phrase.
+		case '<':
+			if last() == opPhrase && len(insts) != repeated {
+				repeat := run(pattern, i)
+				size := 0
+
+				if repeat%2 != 0 {
+					low, high, n, err := parserepeat(pattern[i+repeat:], i+repeat, true)
+					if err != nil && fail(err) {
+						return Simpex{}, errs
+					} else if err != nil {
+						uncombinable = false
+						literal([]byte{char})
+						continue
+					}
+
+					insts[len(insts)-1].phrase.low = low
+					insts[len(insts)-1].phrase.high = high
+					size = n
+					repeated = len(insts)
+				}
+
+				uncombinable = uncombinable && repeat == 1
+
+				literal(bytes.Repeat([]byte{char}, repeat/2))
+				i += repeat + size - 1
+
+				continue
+			}
+
+			// Following a character, a word, or the end of a group, it
+			// repeats that a bounded number of times.
+			if !isrepeatable(last()) || len(insts) == repeated {
+				uncombinable = false
+				literal([]byte{char})
+				continue
+			}
+
+			repeat := run(pattern, i)
+			size := 0
+
+			// Characters and words stay uncombinable, unless followed
+			// by escaped '<'.
+			uncombinable = uncombinable && repeat == 1
+
+			if repeat%2 != 0 {
+				low, high, n, err := parserepeat(pattern[i+repeat:], i+repeat, false)
+				if err != nil && fail(err) {
+					return Simpex{}, errs
+				} else if err != nil {
+					uncombinable = false
+					literal([]byte{char})
+					continue
+				}
+
+				// Repeat the symbol right before, or the whole group
+				// ending right before.
+				start := len(insts) - 1
+				if insts[start].op == opGroupEnd {
+					start = groupstart(insts)
+					uncombinable = false
+				}
+
+				unit := append([]inst{}, insts[start:]...)
+
+				for _, in := range unit {
+					if in.op == opCaptureStart {
+						err = &CompileError{Kind: ErrCaptureInRepetition, Offset: i}
+					}
+				}
+
+				if err == nil && len(unit)*high > maxExpansion {
+					err = &CompileError{Kind: ErrRepetitionTooLarge, Offset: i}
+				}
+
+				if err != nil && fail(err) {
+					return Simpex{}, errs
+				}
+
+				// Leave out the bounds, without repeating anything.
+				if err == nil {
+					// Words are separated by spaces.
+					var separator []inst
+					if unit[0].op == opWord {
+						separator = []inst{{op: opSpace}}
+					}
+
+					insts = append(insts[:start], repetition(unit, separator, low, high)...)
+					repeated = len(insts)
+				}
+
+				size = n
+			}
+
+			literal(bytes.Repeat([]byte{char}, repeat/2))
+			i += repeat + size - 1
+
+			continue
+
+		case '_', '^', '*', '#':
+			if uncombinable && fail(&CompileError{Kind: ErrInvalidCombination, Offset: i}) {
+				return Simpex{}, errs
 			}
 			uncombinable = true
 
 		default:
 			uncombinable = false
+			literal([]byte{char})
+
+			if i == colon {
+				i++
+			}
+
 			continue
 		}
 
 		// Determine how many of the same are repeated.
-		repeat := bytes.IndexFunc(compiled[i:], isnot(char))
+		repeat := run(pattern, i)
+
+		in := inst{op: symbols[char]}
+
+		// Named captures lead with a question mark, their name, and a
+		// colon.
+		name := ""
 
 		// Make sure capture symbols are lined up.
 		if repeat%2 != 0 && char == '{' {
-			if capturing {
-				return nil, fmt.Errorf("unclosed capture at position %d", i)
+			// Carry on as if the previous capture was never opened.
+			if capturing && fail(&CompileError{Kind: ErrUnclosedCapture, Offset: captureOffset}) {
+				return Simpex{}, errs
 			}
 			capturing = true
+			captureOffset = i
+			captureDepth = len(groups)
+
+			if repeat == 1 {
+				name = capturename(pattern[i+1:])
+			}
+
+			// A doubled colon leaves what would be the name as more
+			// of the pattern.
+			if end := i + 2 + len(name); name != "" && end+1 < len(pattern) && pattern[end+1] == ':' {
+				name, colon = "", end
+			}
+
+			for _, n := range names {
+				if name != "" && n == name && fail(&CompileError{
+					Kind:   ErrDuplicateName,
+					Offset: i + 2,
+					Detail: name,
+				}) {
+					return Simpex{}, errs
+				}
+			}
+
+			in.arg = len(names)
+			names = append(names, name)
 		} else if repeat%2 != 0 && char == '}' {
-			if !capturing {
-				return nil, fmt.Errorf("unopened capture at position %d", i)
+			if !capturing && fail(&CompileError{Kind: ErrUnopenedCapture, Offset: i}) {
+				return Simpex{}, errs
+			}
+			if capturing && len(groups) > captureDepth && fail(&CompileError{
+				Kind:   ErrUnclosedGroup,
+				Offset: groups[len(groups)-1],
+			}) {
+				return Simpex{}, errs
 			}
 			capturing = false
-		}
 
-		// Consolidate escaped characters.
-		if repeat > 1 || repeat < 0 {
-			if repeat < 0 {
-				repeat = len(compiled) - i
+			in.arg = len(names) - 1
+		} else if repeat%2 != 0 && char == '*' {
+			in.phrase = &phrase{low: 1, high: -1}
+		} else if repeat%2 != 0 && char == ']' {
+			if fail(&CompileError{Kind: ErrUnopenedClass, Offset: i}) {
+				return Simpex{}, errs
 			}
+		} else if repeat%2 != 0 && char == '(' {
+			groups = append(groups, i)
+		} else if repeat%2 != 0 && (char == '|' || char == ')') {
+			if len(groups) == 0 {
+				kind := ErrUnopenedGroup
+				if char == '|' {
+					kind = ErrAlternationOutsideGroup
+				}
 
-			sequence := bytes.Repeat([]byte{char}, repeat/2)
+				if fail(&CompileError{Kind: kind, Offset: i}) {
+					return Simpex{}, errs
+				}
+
+				// Leave them all as they are, outside of any group.
+				literal(pattern[i : i+repeat])
+				i += repeat - 1
 
-			// For '{' we want the matching symbol before.
-			if repeat%2 != 0 && char == '{' {
-				sequence = append([]byte{matchchars[char]}, sequence...)
-			} else if repeat%2 != 0 {
-				sequence = append(sequence, matchchars[char])
+				continue
+			}
+			if capturing && len(groups) == captureDepth {
+				if fail(&CompileError{Kind: ErrUnclosedCapture, Offset: captureOffset}) {
+					return Simpex{}, errs
+				}
+				capturing = false
 			}
+			if char == ')' {
+				groups = groups[:len(groups)-1]
+			}
+		}
 
-			compiled = append(
-				append(compiled[:i], sequence...),
-				compiled[i+repeat:]...,
-			)
+		// Consolidate escaped characters, with the symbol before them
+		// for '{' and '(' and after them otherwise.
+		escaped := bytes.Repeat([]byte{char}, repeat/2)
 
-			i += repeat/2 + repeat%2 - 1
+		if repeat%2 != 0 && (char == '{' || char == '(') {
+			insts = append(insts, in)
+			literal(escaped)
+		} else if repeat%2 != 0 && char != ']' {
+			literal(escaped)
+			insts = append(insts, in)
+		} else {
+			literal(escaped)
+		}
 
-			continue
+		i += repeat - 1
+
+		if name != "" {
+			i += len(name) + 2
 		}
+	}
+
+	if capturing && fail(&CompileError{Kind: ErrUnclosedCapture, Offset: captureOffset}) {
+		return Simpex{}, errs
+	}
 
-		// Replace the symbol with a matching character.
-		compiled[i] = matchchars[char]
+	for i := len(groups) - 1; i >= 0; i-- {
+		if fail(&CompileError{Kind: ErrUnclosedGroup, Offset: groups[i]}) {
+			return Simpex{}, errs
+		}
 	}
 
-	if capturing {
-		return nil, fmt.Errorf("unclosed capture at position %d", len(compiled)-1)
+	if errs != nil {
+		return Simpex{}, errs
 	}
 
-	return Simpex(compiled), nil
+	link(insts)
+
+	return Simpex{
+		insts: insts,
+		names: names,
+		opts:  opts,
+	}, nil
 }
 
-// Match a text against a pattern to see if it matches. If it does, captured
-// matches are returned. If it doesn't, nil is returned.
-func (sx Simpex) Match(text []byte) [][]byte {
-	captures := [][]byte{}
+// run returns how many times the character at position i of the pattern is
+// repeated in a row, from there on.
+func run(pattern []byte, i int) int {
+	end := i + 1
+	for end < len(pattern) && pattern[end] == pattern[i] {
+		end++
+	}
 
-	var capture []byte
+	return end - i
+}
 
-	for len(sx) > 0 {
-		char := sx[0]
+// class is a set of characters, matched by a character class.
+type class struct {
+	negated bool
 
-		switch char {
-		case captureStart:
-			capture = []byte{}
-			sx = sx[1:]
+	// Pairs of the lowest and highest characters of each range, with
+	// single characters as ranges of one.
+	ranges []rune
+}
 
-		case captureEnd:
-			captures = append(captures, capture)
-			capture = nil
-			sx = sx[1:]
+// parseclass parses a character class, given the pattern following its start
+// symbol and the position of it, returning the class and the size of it in the
+// pattern, including its end symbol, along with any errors. After an error,
+// it carries on parsing as best it can.
+//
+// A leading ^ negates the class and - between two characters makes a range of
+// them, while ]] is an escaped ]. Characters are runes in UTF-8 mode and bytes
+// otherwise.
+func parseclass(pattern []byte, position int, utf8mode bool) (class, int, []*CompileError) {
+	var c class
 
-		case charMatch:
-			if len(text) == 0 {
-				return nil
-			}
+	var errs []*CompileError
 
-			if capture != nil {
-				capture = append(capture, text[0])
-			}
+	decode := func(i int) (rune, int) {
+		if utf8mode {
+			return utf8.DecodeRune(pattern[i:])
+		}
 
-			sx = sx[1:]
-			text = text[1:]
+		return rune(pattern[i]), 1
+	}
 
-		case wordMatch:
-			if len(text) == 0 || isnotalphanum(rune(text[0])) {
-				return nil
-			}
+	i := 0
+	if i < len(pattern) && pattern[i] == '^' {
+		c.negated = true
+		i++
+	}
 
-			// Default to matching the whole word.
-			edge := bytes.IndexFunc(text, isnotalphanum)
-			if edge < 1 {
-				edge = len(text)
-			}
+	for {
+		if i >= len(pattern) {
+			return c, len(pattern), append(errs, &CompileError{
+				Kind:   ErrUnclosedClass,
+				Offset: position - 1,
+			})
+		}
 
-			// The end of the word is matched by static alphanums.
-			if len(sx) > 1 && isalphanum(rune(sx[1])) {
-				start := 1
-				end := bytes.IndexFunc(sx[start:], isnotalphanum) + start
-				if end-start < 0 {
-					end = len(sx)
-				}
+		if pattern[i] == ']' && (i+1 >= len(pattern) || pattern[i+1] != ']') {
+			break
+		}
 
-				edge = bytes.Index(text, sx[start:end])
-				if edge < 0 {
-					return nil
-				}
-			}
+		low, size := decode(i)
 
-			if capture != nil {
-				capture = append(capture, text[:edge]...)
-			}
+		if low == ']' {
+			size++
+		}
+
+		i += size
+		high := low
 
-			sx = sx[1:]
-			text = text[edge:]
+		// A dash before the end of the class is just a dash.
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			high, size = decode(i + 1)
 
-		case phraseMatch:
-			if len(text) == 0 {
-				return nil
+			if high < low {
+				errs = append(errs, &CompileError{Kind: ErrInvalidRange, Offset: position + i})
 			}
 
-			// Default to a very greedy match.
-			edge := len(text)
+			i += 1 + size
+		}
 
-			// Find the beginning of the next following non-symbol
-			// subtext, from where we'll match this phrase.
-			if start := bytes.IndexFunc(sx, isnotsymbol); start >= 0 {
-				end := bytes.IndexFunc(sx[start:], issymbol) + start
-				if end-start < 0 {
-					end = len(sx)
-				}
+		c.ranges = append(c.ranges, low, high)
+	}
 
-				edge = bytes.Index(text, sx[start:end])
-				if edge < 0 {
-					return nil
-				}
-			}
+	if len(c.ranges) == 0 {
+		errs = append(errs, &CompileError{Kind: ErrEmptyClass, Offset: position - 1})
+	}
 
-			if capture != nil {
-				capture = append(capture, text[:edge]...)
-			}
+	return c, i + 1, errs
+}
 
-			sx = sx[1:]
-			text = text[edge:]
+// contains tells whether a character is in any of the ranges of the class,
+// regardless of negation.
+func (c class) contains(r rune) bool {
+	for i := 0; i < len(c.ranges); i += 2 {
+		if r >= c.ranges[i] && r <= c.ranges[i+1] {
+			return true
+		}
+	}
 
-		default:
-			// Either there's no more text to match or the text
-			// doesn't match, so we fail the operation.
-			if len(text) == 0 || char != text[0] {
-				return nil
-			}
+	return false
+}
+
+// phrase is how much text a phrase can match, and in what order it tries.
+type phrase struct {
+	// Whether to try the longest candidate first, rather than the
+	// shortest.
+	greedy bool
+
+	// The lowest and highest number of characters, with -1 for no highest.
+	low, high int
+}
+
+const (
+	// maxRepeat is the most times anything can be repeated, and
+	// maxExpansion the most instructions a repetition can expand into,
+	// to keep compiled patterns within reason.
+	maxRepeat    = 1000
+	maxExpansion = 100000
+)
+
+// parserepeat parses the bounds of a repetition, like 3> or 2,4>, given the
+// pattern following its start symbol and the position of it, returning the
+// lowest and highest number of times to repeat and the size of the bounds in
+// the pattern, including its end symbol. If open, the highest can be left
+// out, like 2,>, and is then -1.
+func parserepeat(pattern []byte, position int, open bool) (int, int, int, *CompileError) {
+	low, i := parsenumber(pattern, 0)
+	if i == 0 {
+		return 0, 0, 0, &CompileError{Kind: ErrInvalidRepetition, Offset: position - 1}
+	}
+
+	high := low
+
+	if i < len(pattern) && pattern[i] == ',' {
+		var size int
+		high, size = parsenumber(pattern, i+1)
+		if size == 0 && open {
+			high = -1
+		} else if size == 0 {
+			return 0, 0, 0, &CompileError{Kind: ErrInvalidRepetition, Offset: position - 1}
+		}
+
+		i += 1 + size
+	}
+
+	if i >= len(pattern) || pattern[i] != '>' {
+		return 0, 0, 0, &CompileError{Kind: ErrUnclosedRepetition, Offset: position - 1}
+	}
+
+	if high < 0 && low <= maxRepeat {
+		return low, high, i + 1, nil
+	}
+
+	if high < 1 || high < low || high > maxRepeat {
+		return 0, 0, 0, &CompileError{Kind: ErrInvalidBounds, Offset: position - 1}
+	}
 
-			if capture != nil {
-				capture = append(capture, text[0])
+	return low, high, i + 1, nil
+}
+
+// parsenumber parses the decimal digits at position i of the pattern, returning
+// their value and how many there are. Values beyond maxRepeat are capped just
+// above it, so as not to overflow.
+func parsenumber(pattern []byte, i int) (int, int) {
+	number, size := 0, 0
+
+	for ; i+size < len(pattern) && isdigit(pattern[i+size]); size++ {
+		number = number*10 + int(pattern[i+size]-'0')
+		if number > maxRepeat {
+			number = maxRepeat + 1
+		}
+	}
+
+	return number, size
+}
+
+// groupstart returns the position of the start of the group ending the
+// instructions.
+func groupstart(insts []inst) int {
+	depth := 0
+
+	for pc := len(insts) - 1; pc >= 0; pc-- {
+		switch insts[pc].op {
+		case opGroupEnd:
+			depth++
+
+		case opGroupStart:
+			depth--
+			if depth == 0 {
+				return pc
 			}
+		}
+	}
+
+	return -1
+}
+
+// repetition expands a unit of instructions repeated between low and high
+// times, with a separator between each. Beyond the lowest number, each further
+// one is an optional group nested in the one before, so that they're matched
+// greedily and always follow on each other.
+func repetition(unit, separator []inst, low, high int) []inst {
+	var optional []inst
+
+	for n := high - 1; n >= low; n-- {
+		group := []inst{{op: opGroupStart}}
+		if n > 0 {
+			group = append(group, separator...)
+		}
+
+		group = append(group, unit...)
+		group = append(group, optional...)
+		optional = append(group, inst{op: opAlternation}, inst{op: opGroupEnd})
+	}
+
+	var expanded []inst
+
+	for n := 0; n < low; n++ {
+		if n > 0 {
+			expanded = append(expanded, separator...)
+		}
+
+		expanded = append(expanded, unit...)
+	}
 
-			sx = sx[1:]
-			text = text[1:]
+	return append(expanded, optional...)
+}
+
+// capturename returns the name leading a capture, given the pattern following
+// its start symbol, or an empty string if there is none. Names follow a
+// question mark, are made up of alphanumerics and underscores, must start with
+// a letter, and are separated from the rest of the capture by a colon.
+func capturename(pattern []byte) string {
+	if len(pattern) == 0 || pattern[0] != '?' {
+		return ""
+	}
+
+	pattern = pattern[1:]
+
+	end := bytes.IndexByte(pattern, ':')
+	if end < 1 || !isalpha(rune(pattern[0])) {
+		return ""
+	}
+
+	for _, char := range pattern[:end] {
+		if !isalphanum(rune(char)) && char != '_' {
+			return ""
 		}
 	}
 
-	// Pattern is exhausted and we still have unmatched text.
-	if len(text) > 0 {
+	return string(pattern[:end])
+}
+
+// SubexpNames returns the names of the captures in the pattern, in the same
+// order as the captures returned by Match(). Unnamed captures have empty
+// names. The slice should not be modified.
+func (sx Simpex) SubexpNames() []string {
+	return sx.names
+}
+
+// Match a text against a pattern to see if it matches. If it does, captured
+// matches are returned, with nil for those in branches of groups not taken.
+// If it doesn't, nil is returned.
+func (sx Simpex) Match(text []byte) [][]byte {
+	indexes := sx.MatchIndex(text)
+	if indexes == nil {
+		return nil
+	}
+
+	return submatches(text, indexes[2:])
+}
+
+// MatchIndex is like Match() but returns where in the text it matched, rather
+// than copies of what. The first pair of start and end offsets spans the
+// whole text, followed by one pair per capture, like
+// regexp.FindSubmatchIndex(). Captures in branches of groups not taken have
+// offsets of -1. If it doesn't match, nil is returned.
+func (sx Simpex) MatchIndex(text []byte) []int {
+	return sx.matchIndex(text, sx.indexes())
+}
+
+// MatchInto is like Match() but returns captures as sub-slices of the text
+// rather than copies, reusing the storage of dst for them. As long as they
+// fit in dst and there are no more than a handful, matching doesn't allocate,
+// unless the pattern and text are long enough that what backtracking has ruled
+// out needs room of its own, like for lines of hundreds of characters. If it
+// doesn't match, nil is returned.
+func (sx Simpex) MatchInto(dst [][]byte, text []byte) [][]byte {
+	var buf [stackIndexes]int
+
+	indexes := sx.matchIndex(text, sx.stackIndexes(buf[:]))
+	if indexes == nil {
 		return nil
 	}
 
-	return captures
+	if dst == nil {
+		dst = [][]byte{}
+	}
+
+	dst = dst[:0]
+	for i := 2; i < len(indexes); i += 2 {
+		if indexes[i] < 0 {
+			dst = append(dst, nil)
+			continue
+		}
+
+		dst = append(dst, text[indexes[i]:indexes[i+1]:indexes[i+1]])
+	}
+
+	return dst
 }
 
-func isalphanum(r rune) bool {
-	return (r >= '0' && r <= '9') ||
-		(r >= 'A' && r <= 'Z') ||
-		(r >= 'a' && r <= 'z')
+// matchIndex matches a text against the pattern, filling indexes with the
+// offsets of the match and its captures. See MatchIndex().
+func (sx Simpex) matchIndex(text []byte, indexes []int) []int {
+	m := matcher{sx: sx, text: text, anchored: true}
+
+	indexes, ok := m.match(0, 0, indexes)
+	if !ok {
+		return nil
+	}
+
+	return indexes
 }
 
-func isnotalphanum(r rune) bool {
-	return !isalphanum(r)
+// indexes allocates room for the start and end offsets of a match and of all
+// its captures.
+func (sx Simpex) indexes() []int {
+	return make([]int, 2+2*len(sx.names))
 }
 
-func issymbol(r rune) bool {
-	return r == rune(captureStart) ||
-		r == rune(captureEnd) ||
-		r == rune(charMatch) ||
-		r == rune(wordMatch) ||
-		r == rune(phraseMatch)
+// stackIndexes is the size of buffers for indexes kept on the stack, fitting
+// the offsets of a match and seven captures.
+const stackIndexes = 16
+
+// stackIndexes is like indexes() but uses buf, if large enough, so that
+// matching doesn't allocate.
+func (sx Simpex) stackIndexes(buf []int) []int {
+	if len(buf) < 2+2*len(sx.names) {
+		return sx.indexes()
+	}
+
+	return buf[:2+2*len(sx.names)]
 }
 
-func isnotsymbol(r rune) bool {
-	return !issymbol(r)
+// subexpIndex returns the index of the capture with the given name, or -1 if
+// there is none.
+func (sx Simpex) subexpIndex(name string) int {
+	for i, n := range sx.names {
+		if n == name {
+			return i
+		}
+	}
+
+	return -1
 }
 
-func isnot(b byte) func(r rune) bool {
-	r := rune(b)
-	return func(rr rune) bool {
-		return r != rr
+// MatchNamed is like Match() but returns named captures by their names. Unnamed
+// captures are left out. If it doesn't match, nil is returned.
+func (sx Simpex) MatchNamed(text []byte) map[string][]byte {
+	matches := sx.Match(text)
+	if matches == nil {
+		return nil
 	}
+
+	named := map[string][]byte{}
+	for i, name := range sx.names {
+		if name != "" {
+			named[name] = matches[i]
+		}
+	}
+
+	return named
+}
+
+// Find the first occurrence of a pattern in a text. Unlike Match(), the
+// pattern doesn't have to cover the whole text. If found, the matched text is
+// returned followed by any captures, like regexp.FindSubmatch(). If not, nil
+// is returned.
+func (sx Simpex) Find(text []byte) [][]byte {
+	indexes := sx.FindIndex(text)
+	if indexes == nil {
+		return nil
+	}
+
+	return submatches(text, indexes)
+}
+
+// FindIndex is like Find() but returns pairs of start and end offsets for the
+// occurrence and its captures, like regexp.FindSubmatchIndex().
+func (sx Simpex) FindIndex(text []byte) []int {
+	m := matcher{sx: sx, text: text}
+
+	return m.find(0)
+}
+
+// FindAll is the 'All' version of Find(), returning successive non-overlapping
+// occurrences of a pattern in a text, like regexp.FindAllSubmatch(). If n is
+// zero or more, at most n occurrences are returned. If none are found, nil is
+// returned.
+func (sx Simpex) FindAll(text []byte, n int) [][][]byte {
+	var all [][][]byte
+
+	for _, indexes := range sx.FindAllIndex(text, n) {
+		all = append(all, submatches(text, indexes))
+	}
+
+	return all
+}
+
+// FindAllIndex is the 'All' version of FindIndex(), like
+// regexp.FindAllSubmatchIndex().
+func (sx Simpex) FindAllIndex(text []byte, n int) [][]int {
+	var all [][]int
+
+	m := matcher{sx: sx, text: text}
+
+	for start, prev := 0, -1; start <= len(text) && (n < 0 || len(all) < n); {
+		indexes := m.find(start)
+		if indexes == nil {
+			break
+		}
+
+		// Empty occurrences abutting the previous one are ignored.
+		if indexes[1] == indexes[0] && indexes[0] == prev {
+			start = m.after(indexes[0])
+			continue
+		}
+
+		all = append(all, indexes)
+
+		prev, start = indexes[1], indexes[1]
+		if indexes[1] == indexes[0] {
+			start = m.after(start)
+		}
+	}
+
+	return all
+}
+
+// submatches copies the spans of a text given by pairs of start and end
+// offsets. Negative offsets, of captures not taking part in the match, give
+// nil spans.
+func submatches(text []byte, indexes []int) [][]byte {
+	matches := make([][]byte, 0, len(indexes)/2)
+	for i := 0; i < len(indexes); i += 2 {
+		if indexes[i] < 0 {
+			matches = append(matches, nil)
+			continue
+		}
+
+		match := append([]byte{}, text[indexes[i]:indexes[i+1]]...)
+		matches = append(matches, match)
+	}
+
+	return matches
+}
+
+// matcher holds the state of matching one text against a pattern.
+type matcher struct {
+	sx   Simpex
+	text []byte
+
+	// Whether the pattern must cover the whole text or only part of it.
+	anchored bool
+
+	// Positions in the pattern and text known not to lead to a match, so
+	// that backtracking never explores the same dead end twice. They're
+	// kept as bits in memo if there's room for all of them, so that small
+	// patterns and texts don't allocate, and otherwise in failed.
+	memo   [memoBits / 64]uint64
+	failed []uint64
+
+	// For phrases, the position in the text from which no end of them is
+	// known to lead to a match, up to the end of the text. Any later
+	// start whose shortest candidate is past it can then fail at once,
+	// so that looking for occurrences isn't quadratic in the length of
+	// the text. It's only kept when matches needn't cover the whole text.
+	dead []int
+}
+
+// memoBits is how many positions in the pattern and text the bits kept by
+// matchers without allocating can cover.
+const memoBits = 2048
+
+// match walks the pattern and the text in tandem, from position pc of the
+// pattern and i of the text. Whenever a symbol could consume varying lengths
+// of text, each candidate is tried in turn and the rest of the pattern
+// matched recursively, so that a choice leading to a dead end can be
+// backtracked.
+//
+// The first two indexes are expected to hold the start and end offsets of the
+// whole match, followed by room for those of captures. They're filled in and
+// indexes is then returned along with whether the pattern matched.
+func (m *matcher) match(pc, i int, indexes []int) ([]int, bool) {
+	for ; pc < len(m.sx.insts); pc++ {
+		in := &m.sx.insts[pc]
+
+		switch in.op {
+		case opLiteral:
+			end, ok := m.prefix(in.literal, i)
+			if !ok {
+				return nil, false
+			}
+
+			i = end
+
+		case opCaptureStart:
+			indexes[2+2*in.arg] = i
+
+		case opCaptureEnd:
+			indexes[3+2*in.arg] = i
+
+		case opGroupStart:
+			if m.hasfailed(pc, i) {
+				return nil, false
+			}
+
+			// Try each branch in order, from right after the symbol
+			// leading it. Captures in the group are reset first, so
+			// those of branches not taken are left out.
+			for branch := pc; m.sx.insts[branch].op != opGroupEnd; branch = m.sx.insts[branch].arg {
+				m.reset(pc, indexes)
+
+				if indexes, ok := m.match(branch+1, i, indexes); ok {
+					return indexes, true
+				}
+			}
+
+			m.fail(pc, i)
+
+			return nil, false
+
+		case opAlternation, opGroupEnd:
+			// A branch has matched, so skip past the others.
+			for m.sx.insts[pc].op != opGroupEnd {
+				pc = m.sx.insts[pc].arg
+			}
+
+			// What follows the group is shared by all its branches,
+			// and by all the groups nested in it, so remember dead
+			// ends here too rather than walking out to them again.
+			if m.hasfailed(pc, i) {
+				return nil, false
+			}
+
+			if indexes, ok := m.match(pc+1, i, indexes); ok {
+				return indexes, true
+			}
+
+			m.fail(pc, i)
+
+			return nil, false
+
+		case opChar:
+			if i >= len(m.text) {
+				return nil, false
+			}
+
+			_, size := m.decode(i)
+			i += size
+
+		case opWord:
+			if m.hasfailed(pc, i) {
+				return nil, false
+			}
+
+			edge := i
+			for edge < len(m.text) {
+				r, size := m.decode(edge)
+				if !m.isword(r) {
+					break
+				}
+
+				edge += size
+			}
+
+			// Words are greedy, so try the longest candidate first.
+			for end := edge; end > i; end -= m.decodeLast(i, end) {
+				if indexes, ok := m.match(pc+1, end, indexes); ok {
+					return indexes, true
+				}
+			}
+
+			m.fail(pc, i)
+
+			return nil, false
+
+		case opSpace:
+			if m.hasfailed(pc, i) {
+				return nil, false
+			}
+
+			edge := i
+			for edge < len(m.text) && isspace(m.text[edge]) {
+				edge++
+			}
+
+			// Spaces are greedy, so try the longest candidate first.
+			for end := edge; end > i; end-- {
+				if indexes, ok := m.match(pc+1, end, indexes); ok {
+					return indexes, true
+				}
+			}
+
+			m.fail(pc, i)
+
+			return nil, false
+
+		case opClass:
+			if i >= len(m.text) {
+				return nil, false
+			}
+
+			r, size := m.decode(i)
+			if !m.inclass(*in.class, r) {
+				return nil, false
+			}
+
+			i += size
+
+		case opNumber:
+			if m.hasfailed(pc, i) {
+				return nil, false
+			}
+
+			start := i
+			if start < len(m.text) && (m.text[start] == '-' || m.text[start] == '+') {
+				start++
+			}
+
+			digits := start
+			for digits < len(m.text) && isdigit(m.text[digits]) {
+				digits++
+			}
+
+			if digits == start {
+				m.fail(pc, i)
+				return nil, false
+			}
+
+			edge := digits
+			if edge+1 < len(m.text) && m.text[edge] == '.' && isdigit(m.text[edge+1]) {
+				edge++
+				for edge < len(m.text) && isdigit(m.text[edge]) {
+					edge++
+				}
+			}
+
+			// Numbers are greedy, so try the longest candidate first,
+			// skipping any decimal point not followed by decimals.
+			for end := edge; end > start; end-- {
+				if end == digits+1 {
+					continue
+				}
+
+				if indexes, ok := m.match(pc+1, end, indexes); ok {
+					return indexes, true
+				}
+			}
+
+			m.fail(pc, i)
+
+			return nil, false
+
+		case opPhrase:
+			p := in.phrase
+
+			// The shortest and longest candidates, within the bounds.
+			low := m.advance(i, p.low)
+			if low < 0 {
+				return nil, false
+			}
+
+			high := len(m.text)
+			if edge := m.advance(i, p.high); p.high >= 0 && edge >= 0 {
+				high = edge
+			}
+
+			// Without anything following, swallow the rest of the text.
+			// When bounds keep it from reaching the end, matches that
+			// needn't cover the whole text take as much of it as lazy
+			// or greedy phrases would.
+			if in.next < 0 {
+				switch {
+				case high == len(m.text):
+					i = high
+				case m.anchored:
+					return nil, false
+				case p.greedy:
+					i = high
+				default:
+					i = low
+				}
+
+				break
+			}
+
+			if m.hasfailed(pc, i) || m.dead != nil && low >= m.dead[pc] {
+				return nil, false
+			}
+
+			// What follows right after, being literal text, a space,
+			// or some other symbol.
+			next := &m.sx.insts[in.next]
+
+			// Lazy phrases try the shortest candidate first and greedy
+			// ones the longest. Only the occurrences of following
+			// literal text or spaces need trying, and otherwise every
+			// character in turn.
+			if p.greedy {
+				for edge := high; edge >= low; edge = m.before(low, edge) {
+					switch next.op {
+					case opLiteral:
+						edge = m.lastindex(next.literal, edge)
+					case opSpace:
+						edge = m.lastindexspace(edge)
+					}
+
+					if edge < low {
+						break
+					}
+
+					if indexes, ok := m.match(pc+1, edge, indexes); ok {
+						return indexes, true
+					}
+				}
+			} else {
+				for edge := low; edge <= high; edge = m.after(edge) {
+					switch next.op {
+					case opLiteral:
+						edge = m.index(next.literal, edge)
+					case opSpace:
+						edge = m.indexspace(edge)
+					}
+
+					if edge < 0 || edge > high {
+						break
+					}
+
+					if indexes, ok := m.match(pc+1, edge, indexes); ok {
+						return indexes, true
+					}
+				}
+			}
+
+			// Every candidate up to the end of the text failed.
+			if m.dead != nil && high == len(m.text) && low < m.dead[pc] {
+				m.dead[pc] = low
+			}
+
+			m.fail(pc, i)
+
+			return nil, false
+		}
+	}
+
+	// Pattern is exhausted, so it's a match only if the text is too.
+	if m.anchored && i != len(m.text) {
+		return nil, false
+	}
+
+	indexes[1] = i
+
+	return indexes, true
+}
+
+// find the leftmost match starting at or after position start of the text,
+// returning its indexes or nil if there is none.
+func (m *matcher) find(start int) []int {
+	indexes := m.sx.indexes()
+
+	if m.dead == nil {
+		m.dead = make([]int, len(m.sx.insts))
+		for pc := range m.dead {
+			m.dead[pc] = len(m.text) + 1
+		}
+	}
+
+	for i := start; i <= len(m.text); {
+		indexes[0] = i
+
+		if indexes, ok := m.match(0, i, indexes); ok {
+			return indexes
+		}
+
+		if i == len(m.text) {
+			break
+		}
+
+		_, size := m.decode(i)
+		i += size
+	}
+
+	return nil
+}
+
+// decode the character at position i of the text, being a rune in UTF-8 mode
+// and a byte otherwise, returning it and its size.
+func (m *matcher) decode(i int) (rune, int) {
+	if m.sx.opts.UTF8 {
+		return utf8.DecodeRune(m.text[i:])
+	}
+
+	return rune(m.text[i]), 1
+}
+
+// decodeLast returns the size of the character ending at position end of the
+// text, without looking before position start.
+func (m *matcher) decodeLast(start, end int) int {
+	if m.sx.opts.UTF8 {
+		_, size := utf8.DecodeLastRune(m.text[start:end])
+		return size
+	}
+
+	return 1
+}
+
+// advance returns the position of the text n characters after position i, or
+// -1 if the text ends before that.
+func (m *matcher) advance(i, n int) int {
+	if !m.sx.opts.UTF8 {
+		if i+n > len(m.text) {
+			return -1
+		}
+
+		return i + n
+	}
+
+	for ; n > 0; n-- {
+		if i >= len(m.text) {
+			return -1
+		}
+
+		_, size := utf8.DecodeRune(m.text[i:])
+		i += size
+	}
+
+	return i
+}
+
+// after returns the position of the text right after the character at
+// position i, or past the end of the text if it ends there.
+func (m *matcher) after(i int) int {
+	if i >= len(m.text) {
+		return i + 1
+	}
+
+	_, size := m.decode(i)
+
+	return i + size
+}
+
+// before returns the position of the text right before the character ending
+// at position i, without looking before position start, or before start if i
+// is there.
+func (m *matcher) before(start, i int) int {
+	if i <= start {
+		return i - 1
+	}
+
+	return i - m.decodeLast(start, i)
+}
+
+// equal tells whether the literal character at the start of a pattern's
+// literal text matches the one at position i of the text, returning their
+// sizes.
+func (m *matcher) equal(literal []byte, i int) (int, int, bool) {
+	p, t := literal[0], m.text[i]
+
+	if !m.sx.opts.CaseInsensitive {
+		return 1, 1, p == t
+	}
+
+	if !m.sx.opts.UTF8 || p < utf8.RuneSelf && t < utf8.RuneSelf {
+		return 1, 1, lower(p) == lower(t)
+	}
+
+	pr, psize := utf8.DecodeRune(literal)
+	tr, tsize := utf8.DecodeRune(m.text[i:])
+
+	// Invalid encodings are compared byte for byte.
+	if pr == utf8.RuneError && psize == 1 || tr == utf8.RuneError && tsize == 1 {
+		return 1, 1, p == t
+	}
+
+	return psize, tsize, equalfold(pr, tr)
+}
+
+// index returns the position of the first occurrence in the text, at or after
+// position i, of a pattern's literal text. If there is none, -1 is returned.
+func (m *matcher) index(literal []byte, i int) int {
+	if !m.sx.opts.CaseInsensitive {
+		edge := bytes.Index(m.text[i:], literal)
+		if edge < 0 {
+			return -1
+		}
+
+		return i + edge
+	}
+
+	for ; i <= len(m.text); i++ {
+		if _, ok := m.prefix(literal, i); ok {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// lastindex returns the position of the last occurrence in the text, at or
+// before position i, of a pattern's literal text. If there is none, -1 is
+// returned.
+func (m *matcher) lastindex(literal []byte, i int) int {
+	if !m.sx.opts.CaseInsensitive {
+		limit := i + len(literal)
+		if limit > len(m.text) {
+			limit = len(m.text)
+		}
+
+		return bytes.LastIndex(m.text[:limit], literal)
+	}
+
+	for ; i >= 0; i-- {
+		if _, ok := m.prefix(literal, i); ok {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// indexspace returns the position of the first space or tab in the text, at
+// or after position i. If there is none, -1 is returned.
+func (m *matcher) indexspace(i int) int {
+	edge := bytes.IndexAny(m.text[i:], " \t")
+	if edge < 0 {
+		return -1
+	}
+
+	return i + edge
+}
+
+// lastindexspace returns the position of the last space or tab in the text, at
+// or before position i. If there is none, -1 is returned.
+func (m *matcher) lastindexspace(i int) int {
+	if i >= len(m.text) {
+		i = len(m.text) - 1
+	}
+
+	return bytes.LastIndexAny(m.text[:i+1], " \t")
+}
+
+// prefix tells whether the text at position i begins with a pattern's literal
+// text, returning where it ends in the text.
+func (m *matcher) prefix(literal []byte, i int) (int, bool) {
+	if !m.sx.opts.CaseInsensitive {
+		if !bytes.HasPrefix(m.text[i:], literal) {
+			return 0, false
+		}
+
+		return i + len(literal), true
+	}
+
+	for len(literal) > 0 {
+		if i >= len(m.text) {
+			return 0, false
+		}
+
+		psize, tsize, ok := m.equal(literal, i)
+		if !ok {
+			return 0, false
+		}
+
+		literal = literal[psize:]
+		i += tsize
+	}
+
+	return i, true
+}
+
+// inclass tells whether a character is in a class, in case-insensitive mode
+// regardless of case.
+func (m *matcher) inclass(c class, r rune) bool {
+	in := c.contains(r)
+
+	if !in && m.sx.opts.CaseInsensitive && m.sx.opts.UTF8 {
+		for f := unicode.SimpleFold(r); f != r && !in; f = unicode.SimpleFold(f) {
+			in = c.contains(f)
+		}
+	} else if !in && m.sx.opts.CaseInsensitive {
+		in = c.contains(rune(lower(byte(r)))) || c.contains(rune(upper(byte(r))))
+	}
+
+	return in != c.negated
+}
+
+// isword tells whether a character is part of words.
+func (m *matcher) isword(r rune) bool {
+	if m.sx.opts.WordChars != "" {
+		if m.sx.opts.UTF8 && strings.ContainsRune(m.sx.opts.WordChars, r) {
+			return true
+		} else if !m.sx.opts.UTF8 && strings.IndexByte(m.sx.opts.WordChars, byte(r)) >= 0 {
+			return true
+		}
+	}
+
+	if m.sx.opts.IsWord != nil {
+		return m.sx.opts.IsWord(r)
+	}
+
+	if m.sx.opts.UTF8 {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	return isalphanum(r)
+}
+
+// reset the indexes of all captures in the group starting at pc.
+func (m *matcher) reset(pc int, indexes []int) {
+	captures := m.sx.insts[pc].captures
+	for n := captures[0]; n < captures[1]; n++ {
+		indexes[2+2*n], indexes[3+2*n] = -1, -1
+	}
+}
+
+func (m *matcher) hasfailed(pc, i int) bool {
+	key := pc*(len(m.text)+1) + i
+
+	if len(m.sx.insts)*(len(m.text)+1) <= memoBits {
+		return m.memo[key/64]&(1<<(key%64)) != 0
+	}
+
+	return m.failed != nil && m.failed[key/64]&(1<<(key%64)) != 0
+}
+
+func (m *matcher) fail(pc, i int) {
+	key := pc*(len(m.text)+1) + i
+
+	if len(m.sx.insts)*(len(m.text)+1) <= memoBits {
+		m.memo[key/64] |= 1 << (key % 64)
+		return
+	}
+
+	if m.failed == nil {
+		m.failed = make([]uint64, (len(m.sx.insts)*(len(m.text)+1)+63)/64)
+	}
+
+	m.failed[key/64] |= 1 << (key % 64)
+}
+
+// equalfold tells whether two runes are equal under Unicode case folding.
+func equalfold(a, b rune) bool {
+	if a == b {
+		return true
+	}
+
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lower returns the lowercase version of an ASCII letter, or any other byte
+// as it is.
+func lower(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+
+	return b
+}
+
+// upper returns the uppercase version of an ASCII letter, or any other byte
+// as it is.
+func upper(b byte) byte {
+	if b >= 'a' && b <= 'z' {
+		return b - 'a' + 'A'
+	}
+
+	return b
+}
+
+func isalpha(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+}
+
+func isalphanum(r rune) bool {
+	return (r >= '0' && r <= '9') || isalpha(r)
+}
+
+func isdigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isspace(b byte) bool {
+	return b == ' ' || b == '\t'
+}
+
+func isrepeatable(o op) bool {
+	return o == opChar || o == opWord || o == opGroupEnd
 }
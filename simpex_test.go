@@ -1,10 +1,15 @@
 package simpex_test
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/tobiassjosten/go-simpex"
 )
@@ -35,6 +40,11 @@ func TestCompile(t *testing.T) {
 			error:   true,
 		},
 
+		"handle unclosed capture before escaped end symbols": {
+			pattern: []byte("{}}"),
+			error:   true,
+		},
+
 		"handle nested capture symbols": {
 			pattern: []byte("{Lorem {ipsum} dolor} sit amet."),
 			error:   true,
@@ -65,6 +75,46 @@ func TestCompile(t *testing.T) {
 			sx:      []byte("\x02{{}\x03 \x02\x1d\x03 \x02*\x03 \x02*\x1d\x03 \x02\x1e\x03 \x02^\x03 \x02^\x1e\x03 \x02\x1f\x03 \x02_\x03 \x02_\x1f\x03"),
 		},
 
+		"named captures": {
+			pattern: []byte("{?first:Lorem} {^} {?last_1:^}."),
+			sx:      []byte("\x02Lorem\x03 \x02\x1e\x03 \x02\x1e\x03."),
+		},
+
+		"named capture with colon": {
+			pattern: []byte("{?name:Lorem: ipsum}"),
+			sx:      []byte("\x02Lorem: ipsum\x03"),
+		},
+
+		"unnamed capture with colon": {
+			pattern: []byte("{Lorem ipsum: dolor}"),
+			sx:      []byte("\x02Lorem ipsum: dolor\x03"),
+		},
+
+		"unnamed capture with leading digit": {
+			pattern: []byte("{1st: ^}"),
+			sx:      []byte("\x02" + "1st: \x1e\x03"),
+		},
+
+		"unmarked capture name": {
+			pattern: []byte("{Say: *}"),
+			sx:      []byte("\x02Say: \x1d\x03"),
+		},
+
+		"escaped capture name": {
+			pattern: []byte("{?Say:: *}"),
+			sx:      []byte("\x02?Say: \x1d\x03"),
+		},
+
+		"escaped capture with colon": {
+			pattern: []byte("{{name:Lorem}}"),
+			sx:      []byte("{name:Lorem}"),
+		},
+
+		"disallow duplicate capture names": {
+			pattern: []byte("{?name:^} {?name:^}"),
+			error:   true,
+		},
+
 		"disallow character word combination": {
 			pattern: []byte("_^"),
 			error:   true,
@@ -95,28 +145,258 @@ func TestCompile(t *testing.T) {
 			error:   true,
 		},
 
-		"reserved capture start symbol": {
+		"literal capture start symbol": {
 			pattern: []byte("\x02"),
-			error:   true,
+			sx:      []byte("\x02"),
 		},
 
-		"reserved capture end symbol": {
+		"literal capture end symbol": {
 			pattern: []byte("\x03"),
-			error:   true,
+			sx:      []byte("\x03"),
 		},
 
-		"reserved character symbol": {
+		"literal character symbol": {
 			pattern: []byte("\x1f"),
-			error:   true,
+			sx:      []byte("\x1f"),
 		},
 
-		"reserved word symbol": {
+		"literal word symbol": {
 			pattern: []byte("\x1e"),
-			error:   true,
+			sx:      []byte("\x1e"),
 		},
 
-		"reserved phrase symbol": {
+		"literal phrase symbol": {
 			pattern: []byte("\x1d"),
+			sx:      []byte("\x1d"),
+		},
+
+		"escape and handle number symbols": {
+			pattern: []byte("Lorem # ## ###."),
+			sx:      []byte("Lorem \x1c # #\x1c."),
+		},
+
+		"handle invalid number combinations": {
+			pattern: []byte("Lorem #^"),
+			error:   true,
+		},
+
+		"literal number symbol": {
+			pattern: []byte("\x1c"),
+			sx:      []byte("\x1c"),
+		},
+
+		"escape and handle space symbols": {
+			pattern: []byte("Lorem~~ ~ ~~~."),
+			sx:      []byte("Lorem~ \x01 ~\x01."),
+		},
+
+		"combined space symbols": {
+			pattern: []byte("^~^~*"),
+			sx:      []byte("\x1e\x01\x1e\x01\x1d"),
+		},
+
+		"literal space symbol": {
+			pattern: []byte("\x01"),
+			sx:      []byte("\x01"),
+		},
+
+		"escape and handle class symbols": {
+			pattern: []byte("Lorem [[ipsum]] [[[aeiou] [^a-z]]]"),
+			sx:      []byte("Lorem [ipsum] [\x07 \x07"),
+		},
+
+		"combined class symbols": {
+			pattern: []byte("{[ns]}^[_^*{}]"),
+			sx:      []byte("\x02\x07\x03\x1e\x07"),
+		},
+
+		"handle unopened class symbols": {
+			pattern: []byte("Lorem] ipsum"),
+			error:   true,
+		},
+
+		"handle unclosed class symbols": {
+			pattern: []byte("Lorem [ipsum"),
+			error:   true,
+		},
+
+		"handle empty class": {
+			pattern: []byte("Lorem []"),
+			error:   true,
+		},
+
+		"handle empty negated class": {
+			pattern: []byte("Lorem [^]"),
+			error:   true,
+		},
+
+		"handle invalid class range": {
+			pattern: []byte("Lorem [z-a]"),
+			error:   true,
+		},
+
+		"literal class symbol": {
+			pattern: []byte("\x07"),
+			sx:      []byte("\x07"),
+		},
+
+		"literal symbol in class": {
+			pattern: []byte("[a\x1e]"),
+			sx:      []byte("\x07"),
+		},
+
+		"escape and handle group symbols": {
+			pattern: []byte("((( ((Lorem|ipsum|||dolor) )) (sit||||amet)))."),
+			sx:      []byte("\x04( (Lorem\x05ipsum|\x05dolor\x06 ) \x04sit||amet)\x06."),
+		},
+
+		"group in capture": {
+			pattern: []byte("{(Lorem|ipsum)} dolor"),
+			sx:      []byte("\x02\x04Lorem\x05ipsum\x06\x03 dolor"),
+		},
+
+		"capture in group": {
+			pattern: []byte("({Lorem}|{ipsum}) dolor"),
+			sx:      []byte("\x04\x02Lorem\x03\x05\x02ipsum\x03\x06 dolor"),
+		},
+
+		"nested groups": {
+			pattern: []byte("(Lorem (ipsum|dolor)|sit) amet"),
+			sx:      []byte("\x04Lorem \x04ipsum\x05dolor\x06\x05sit\x06 amet"),
+		},
+
+		"combined symbols in branches": {
+			pattern: []byte("(^|*)"),
+			sx:      []byte("\x04\x1e\x05\x1d\x06"),
+		},
+
+		"optional group": {
+			pattern: []byte("You feel (much )?better."),
+			sx:      []byte("You feel \x04much \x05\x06better."),
+		},
+
+		"escape and handle optional group symbols": {
+			pattern: []byte("(Lorem)?? (ipsum)??? dolor? ((sit))?"),
+			sx:      []byte("\x04Lorem\x06? \x04ipsum\x05\x06? dolor? (sit)?"),
+		},
+
+		"optional alternation group": {
+			pattern: []byte("(Lorem|ipsum)?"),
+			sx:      []byte("\x04Lorem\x05ipsum\x05\x06"),
+		},
+
+		"handle unopened group symbols": {
+			pattern: []byte("Lorem) ipsum"),
+			error:   true,
+		},
+
+		"handle unclosed group symbols": {
+			pattern: []byte("(Lorem|ipsum"),
+			error:   true,
+		},
+
+		"handle alternation outside group": {
+			pattern: []byte("Lorem|ipsum"),
+			error:   true,
+		},
+
+		"handle capture closed outside group": {
+			pattern: []byte("({Lorem|ipsum})"),
+			error:   true,
+		},
+
+		"handle capture closed inside group": {
+			pattern: []byte("{(Lorem}|ipsum)"),
+			error:   true,
+		},
+
+		"literal group symbols": {
+			pattern: []byte("\x04\x05\x06"),
+			sx:      []byte("\x04\x05\x06"),
+		},
+
+		"repeated characters": {
+			pattern: []byte("_<3> _<1,3>"),
+			sx:      []byte("\x1f\x1f\x1f \x1f\x04\x1f\x04\x1f\x05\x06\x05\x06"),
+		},
+
+		"repeated words": {
+			pattern: []byte("^<2> ^<0,2>"),
+			sx:      []byte("\x1e\x01\x1e \x04\x1e\x04\x01\x1e\x05\x06\x05\x06"),
+		},
+
+		"repeated group": {
+			pattern: []byte("(a|[b])<2>"),
+			sx:      []byte("\x04a\x05\x07\x06\x04a\x05\x07\x06"),
+		},
+
+		"escape and handle repetition symbols": {
+			pattern: []byte("_<<3> _<<<2> __<2> Lorem<2> (a)<2>?"),
+			sx:      []byte("\x1f<3> \x1f\x1f< _<2> Lorem<2> \x04a\x06\x04a\x06?"),
+		},
+
+		"handle unclosed repetition": {
+			pattern: []byte("_<3"),
+			error:   true,
+		},
+
+		"handle invalid repetition": {
+			pattern: []byte("_<a>"),
+			error:   true,
+		},
+
+		"handle invalid repetition bounds": {
+			pattern: []byte("_<3,2>"),
+			error:   true,
+		},
+
+		"handle empty repetition": {
+			pattern: []byte("_<0>"),
+			error:   true,
+		},
+
+		"handle excessive repetition": {
+			pattern: []byte("_<1001>"),
+			error:   true,
+		},
+
+		"handle excessive nested repetition": {
+			pattern: []byte("( (_<100>)<100>)<100>"),
+			error:   true,
+		},
+
+		"disallow capture in repeated group": {
+			pattern: []byte("({Lorem})<2>"),
+			error:   true,
+		},
+
+		"greedy and bounded phrases": {
+			pattern: []byte("*+ *<2,5> *+<2,> *+<3> *<3>+"),
+			sx:      []byte("\x1d \x1d \x1d \x1d \x1d+"),
+		},
+
+		"escape and handle greedy and bounded phrase symbols": {
+			pattern: []byte("*++ *+++ *<<2> *<<<2> ++ <<"),
+			sx:      []byte("\x1d+ \x1d+ \x1d<2> \x1d< ++ <<"),
+		},
+
+		"handle invalid phrase bounds": {
+			pattern: []byte("*<5,2>"),
+			error:   true,
+		},
+
+		"handle unclosed phrase bounds": {
+			pattern: []byte("*<5"),
+			error:   true,
+		},
+
+		"disallow greedy phrase word combination": {
+			pattern: []byte("*+^"),
+			error:   true,
+		},
+
+		"disallow repeated character word combination": {
+			pattern: []byte("_<2>^"),
 			error:   true,
 		},
 	}
@@ -131,8 +411,11 @@ func TestCompile(t *testing.T) {
 				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
 			}
 
-			if string(tc.sx) != string(sx) {
-				t.Fatalf("Compile(%q)\ngot  %q\nwant %q", tc.pattern, sx, tc.sx)
+			if string(tc.sx) != string(simpex.Program(sx)) {
+				t.Fatalf(
+					"Compile(%q)\ngot  %q\nwant %q",
+					tc.pattern, simpex.Program(sx), tc.sx,
+				)
 			}
 		})
 	}
@@ -238,6 +521,15 @@ func TestMatch(t *testing.T) {
 			pattern: []byte("^df"),
 			text:    []byte("asdd"),
 		},
+		"word match non-word prefix": {
+			pattern: []byte("^df"),
+			text:    []byte("as-df"),
+		},
+		"word match backtracking": {
+			pattern: []byte("{^}a{^}"),
+			text:    []byte("banana1"),
+			matches: [][]byte{[]byte("banan"), []byte("1")},
+		},
 		"word match simple": {
 			pattern: []byte("Lorem ^ dolor sit amet."),
 			text:    []byte("Lorem ipsum dolor sit amet."),
@@ -388,6 +680,25 @@ func TestMatch(t *testing.T) {
 			pattern: []byte("* amet"),
 			text:    []byte("asdf"),
 		},
+		"phrase match empty following": {
+			pattern: []byte("*!"),
+			text:    []byte("!"),
+		},
+		"phrase match backtracking": {
+			pattern: []byte("{*}, {^}!"),
+			text:    []byte("Well, well, hello!"),
+			matches: [][]byte{[]byte("Well, well"), []byte("hello")},
+		},
+		"phrase match backtracking twice": {
+			pattern: []byte("{*} is {*}."),
+			text:    []byte("this is what it is. this is."),
+			matches: [][]byte{[]byte("this"), []byte("what it is. this is")},
+		},
+		"phrase match backtracking dead end": {
+			pattern: []byte("{*} is {^}."),
+			text:    []byte("this is what it is Bob."),
+			matches: [][]byte{[]byte("this is what it"), []byte("Bob")},
+		},
 
 		"combination match simple": {
 			pattern: []byte("Lorem ^ do_or *."),
@@ -430,6 +741,404 @@ func TestMatch(t *testing.T) {
 			text:    []byte("0"),
 			error:   true,
 		},
+
+		"alternation first branch": {
+			pattern: []byte("You (hit|miss) the {^}."),
+			text:    []byte("You hit the rat."),
+			matches: [][]byte{[]byte("rat")},
+		},
+		"alternation last branch": {
+			pattern: []byte("You (hit|miss) the {^}."),
+			text:    []byte("You miss the rat."),
+			matches: [][]byte{[]byte("rat")},
+		},
+		"alternation mismatch": {
+			pattern: []byte("You (hit|miss) the {^}."),
+			text:    []byte("You kiss the rat."),
+		},
+		"alternation captured": {
+			pattern: []byte("You {(hit|miss)} the {^}."),
+			text:    []byte("You miss the rat."),
+			matches: [][]byte{[]byte("miss"), []byte("rat")},
+		},
+		"alternation with symbols": {
+			pattern: []byte("{^} (says|tells you), \"{*}\""),
+			text:    []byte("Bob tells you, \"Hello there.\""),
+			matches: [][]byte{[]byte("Bob"), []byte("Hello there.")},
+		},
+		"alternation empty branch": {
+			pattern: []byte("You feel (much |)better."),
+			text:    []byte("You feel better."),
+			matches: [][]byte{},
+		},
+		"alternation backtracking": {
+			pattern: []byte("{(Lorem|Lorem ipsum)} {^}."),
+			text:    []byte("Lorem ipsum dolor."),
+			matches: [][]byte{[]byte("Lorem ipsum"), []byte("dolor")},
+		},
+		"alternation nested": {
+			pattern: []byte("(Lorem (ipsum|dolor)|sit) amet."),
+			text:    []byte("Lorem dolor amet."),
+			matches: [][]byte{},
+		},
+		"alternation captures of branches not taken": {
+			pattern: []byte("({^} dolor|{^} {^} amet)."),
+			text:    []byte("Lorem ipsum amet."),
+			matches: [][]byte{nil, []byte("Lorem"), []byte("ipsum")},
+		},
+		"alternation captures of branches backtracked": {
+			pattern: []byte("({^} {^}|{*}) sit amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{nil, nil, []byte("Lorem ipsum dolor")},
+		},
+		"alternation escaped": {
+			pattern: []byte("((Lorem||ipsum))"),
+			text:    []byte("(Lorem|ipsum)"),
+			matches: [][]byte{},
+		},
+		"alternation outside group": {
+			pattern: []byte("Lorem|ipsum"),
+			text:    []byte("Lorem"),
+			error:   true,
+		},
+
+		"number match integer": {
+			pattern: []byte("HP: {#}"),
+			text:    []byte("HP: 120"),
+			matches: [][]byte{[]byte("120")},
+		},
+		"number match signed": {
+			pattern: []byte("{#} {#}"),
+			text:    []byte("-12 +7"),
+			matches: [][]byte{[]byte("-12"), []byte("+7")},
+		},
+		"number match decimal": {
+			pattern: []byte("Weight: {#} kg"),
+			text:    []byte("Weight: 12.50 kg"),
+			matches: [][]byte{[]byte("12.50")},
+		},
+		"number match before point": {
+			pattern: []byte("You have {#}."),
+			text:    []byte("You have 12."),
+			matches: [][]byte{[]byte("12")},
+		},
+		"number match before decimal": {
+			pattern: []byte("Version {#}.{#}.{#}"),
+			text:    []byte("Version 1.2.3"),
+			matches: [][]byte{[]byte("1"), []byte("2"), []byte("3")},
+		},
+		"number match backtracking": {
+			pattern: []byte("{#}5"),
+			text:    []byte("125"),
+			matches: [][]byte{[]byte("12")},
+		},
+		"number mismatch word": {
+			pattern: []byte("HP: {#}"),
+			text:    []byte("HP: full"),
+		},
+		"number mismatch sign only": {
+			pattern: []byte("{#}"),
+			text:    []byte("-"),
+		},
+		"number mismatch point only": {
+			pattern: []byte("{#}"),
+			text:    []byte(".5"),
+		},
+		"number escaped": {
+			pattern: []byte("Room ## {#}"),
+			text:    []byte("Room # 12"),
+			matches: [][]byte{[]byte("12")},
+		},
+
+		"space match one": {
+			pattern: []byte("Name:~{^}"),
+			text:    []byte("Name: Bob"),
+			matches: [][]byte{[]byte("Bob")},
+		},
+		"space match many": {
+			pattern: []byte("{^}~{#}~{#}"),
+			text:    []byte("Bob   \t 12\t\t3"),
+			matches: [][]byte{[]byte("Bob"), []byte("12"), []byte("3")},
+		},
+		"space match captured": {
+			pattern: []byte("Name:{~}Bob"),
+			text:    []byte("Name: \tBob"),
+			matches: [][]byte{[]byte(" \t")},
+		},
+		"space match after phrase": {
+			pattern: []byte("{*}~{^}"),
+			text:    []byte("Lorem ipsum  dolor"),
+			matches: [][]byte{[]byte("Lorem ipsum"), []byte("dolor")},
+		},
+		"space match backtracking": {
+			pattern: []byte("Lorem~{*}"),
+			text:    []byte("Lorem   ipsum"),
+			matches: [][]byte{[]byte("ipsum")},
+		},
+		"space match backtracking into spaces": {
+			pattern: []byte("Lorem~ {^}"),
+			text:    []byte("Lorem   ipsum"),
+			matches: [][]byte{[]byte("ipsum")},
+		},
+		"space mismatch none": {
+			pattern: []byte("Name:~{^}"),
+			text:    []byte("Name:Bob"),
+		},
+		"space mismatch newline": {
+			pattern: []byte("Name:~{^}"),
+			text:    []byte("Name:\nBob"),
+		},
+		"space escaped": {
+			pattern: []byte("~~{^}"),
+			text:    []byte("~Bob"),
+			matches: [][]byte{[]byte("Bob")},
+		},
+
+		"class match": {
+			pattern: []byte("You go {[nsewud]}."),
+			text:    []byte("You go e."),
+			matches: [][]byte{[]byte("e")},
+		},
+		"class mismatch": {
+			pattern: []byte("You go {[nsewud]}."),
+			text:    []byte("You go x."),
+		},
+		"class match range": {
+			pattern: []byte("[A-Z][a-z0-9_][a-z0-9_]"),
+			text:    []byte("Ab_"),
+			matches: [][]byte{},
+		},
+		"class mismatch range": {
+			pattern: []byte("[A-Z][a-z]"),
+			text:    []byte("aB"),
+		},
+		"class match negated": {
+			pattern: []byte("{[^0-9]}2"),
+			text:    []byte("R2"),
+			matches: [][]byte{[]byte("R")},
+		},
+		"class mismatch negated": {
+			pattern: []byte("[^0-9]2"),
+			text:    []byte("22"),
+		},
+		"class match one byte": {
+			pattern: []byte("[ab]"),
+			text:    []byte("ab"),
+		},
+		"class match dashes": {
+			pattern: []byte("[-a][a-]"),
+			text:    []byte("--"),
+			matches: [][]byte{},
+		},
+		"class match caret": {
+			pattern: []byte("[a^]"),
+			text:    []byte("^"),
+			matches: [][]byte{},
+		},
+		"class match escaped bracket": {
+			pattern: []byte("[[{[]]a]}]]"),
+			text:    []byte("[]]"),
+			matches: [][]byte{[]byte("]")},
+		},
+		"class match symbols": {
+			pattern: []byte("[*_{(|]"),
+			text:    []byte("|"),
+			matches: [][]byte{},
+		},
+		"class backtracking": {
+			pattern: []byte("{*}[0-9]"),
+			text:    []byte("a1b2"),
+			matches: [][]byte{[]byte("a1b")},
+		},
+
+		"optional present": {
+			pattern: []byte("You feel (much )?better."),
+			text:    []byte("You feel much better."),
+			matches: [][]byte{},
+		},
+		"optional absent": {
+			pattern: []byte("You feel (much )?better."),
+			text:    []byte("You feel better."),
+			matches: [][]byte{},
+		},
+		"optional mismatch": {
+			pattern: []byte("You feel (much )?better."),
+			text:    []byte("You feel so better."),
+		},
+		"optional capture present": {
+			pattern: []byte("You have {^} gold( and {^} silver)?."),
+			text:    []byte("You have 10 gold and 5 silver."),
+			matches: [][]byte{[]byte("10"), []byte("5")},
+		},
+		"optional capture absent": {
+			pattern: []byte("You have {^} gold( and {^} silver)?."),
+			text:    []byte("You have 10 gold."),
+			matches: [][]byte{[]byte("10"), nil},
+		},
+		"optional captured": {
+			pattern: []byte("{(much )?}better"),
+			text:    []byte("better"),
+			matches: [][]byte{{}},
+		},
+		"optional backtracking": {
+			pattern: []byte("{*}( {^})? dolor."),
+			text:    []byte("Lorem ipsum dolor."),
+			matches: [][]byte{[]byte("Lorem"), []byte("ipsum")},
+		},
+		"optional followed by question mark": {
+			pattern: []byte("How are you( doing)???"),
+			text:    []byte("How are you?"),
+			matches: [][]byte{},
+		},
+
+		"repeated characters": {
+			pattern: []byte("Code: {_<3>}"),
+			text:    []byte("Code: a1b"),
+			matches: [][]byte{[]byte("a1b")},
+		},
+		"repeated characters mismatch": {
+			pattern: []byte("Code: {_<3>}"),
+			text:    []byte("Code: a1"),
+		},
+		"repeated characters range": {
+			pattern: []byte("{_<2,4>}.{_<2,4>}"),
+			text:    []byte("abc.def"),
+			matches: [][]byte{[]byte("abc"), []byte("def")},
+		},
+		"repeated characters range too long": {
+			pattern: []byte("{_<2,4>}"),
+			text:    []byte("abcde"),
+		},
+		"repeated words": {
+			pattern: []byte("{^<2,4>} says hello."),
+			text:    []byte("The old  man says hello."),
+			matches: [][]byte{[]byte("The old  man")},
+		},
+		"repeated words too few": {
+			pattern: []byte("{^<2,4>} says hello."),
+			text:    []byte("Tobias says hello."),
+		},
+		"repeated words backtracking": {
+			pattern: []byte("{^<1,3>} {^<2>}"),
+			text:    []byte("a b c d"),
+			matches: [][]byte{[]byte("a b"), []byte("c d")},
+		},
+		"repeated group": {
+			pattern: []byte("{(ha|he)<2,3>}!"),
+			text:    []byte("hahehe!"),
+			matches: [][]byte{[]byte("hahehe")},
+		},
+		"repeated group too many": {
+			pattern: []byte("{(ha|he)<2,3>}!"),
+			text:    []byte("hahahaha!"),
+		},
+		"repeated optional": {
+			pattern: []byte("Lorem{( ipsum)<0,2>} dolor"),
+			text:    []byte("Lorem dolor"),
+			matches: [][]byte{{}},
+		},
+		"greedy phrase": {
+			pattern: []byte("{*+} says, \"{*}\""),
+			text:    []byte("Tobias says, \"Bob says, \"hi\"\""),
+			matches: [][]byte{[]byte("Tobias says, \"Bob"), []byte("hi\"")},
+		},
+		"lazy and greedy phrases": {
+			pattern: []byte("{*}, {*+}, {*}"),
+			text:    []byte("a, b, c, d"),
+			matches: [][]byte{[]byte("a"), []byte("b, c"), []byte("d")},
+		},
+		"greedy phrase before spaces": {
+			pattern: []byte("{*+}~{^}"),
+			text:    []byte("a b c"),
+			matches: [][]byte{[]byte("a b"), []byte("c")},
+		},
+		"greedy phrase backtracking": {
+			pattern: []byte("{*+}, {*} dolor"),
+			text:    []byte("Lorem, ipsum, sit dolor, amet"),
+		},
+		"bounded phrase": {
+			pattern: []byte("{*<3,5>}, {*}"),
+			text:    []byte("a, b, cdef, g"),
+			matches: [][]byte{[]byte("a, b"), []byte("cdef, g")},
+		},
+		"bounded phrase too short": {
+			pattern: []byte("{*<3,>}"),
+			text:    []byte("ab"),
+		},
+		"bounded phrase too long": {
+			pattern: []byte("{*<1,3>}."),
+			text:    []byte("abcd."),
+		},
+		"bounded phrase without lowest": {
+			pattern: []byte("{*<,3>}"),
+			text:    []byte("abc"),
+			error:   true,
+		},
+		"bounded trailing phrase": {
+			pattern: []byte("Lorem {*<1,5>}"),
+			text:    []byte("Lorem ipsum dolor"),
+		},
+		"bounded greedy phrase": {
+			pattern: []byte("{*+<1,4>}.{*}"),
+			text:    []byte("a.b.c.d"),
+			matches: [][]byte{[]byte("a.b"), []byte("c.d")},
+		},
+		"plus after phrase bounds": {
+			pattern: []byte("{*<1,3>}+{*}"),
+			text:    []byte("a+b+c"),
+			matches: [][]byte{[]byte("a"), []byte("b+c")},
+		},
+		"plus after greedy phrase bounds": {
+			pattern: []byte("{*+<1,3>}+1"),
+			text:    []byte("ab+1"),
+			matches: [][]byte{[]byte("ab")},
+		},
+		"empty bounded phrase": {
+			pattern: []byte("Lorem {*<0,5>}."),
+			text:    []byte("Lorem ."),
+			matches: [][]byte{{}},
+		},
+		"escaped greedy phrase": {
+			pattern: []byte("*++"),
+			text:    []byte("a+"),
+			matches: [][]byte{},
+		},
+
+		"escaped repetition": {
+			pattern: []byte("_<<3>"),
+			text:    []byte("a<3>"),
+			matches: [][]byte{},
+		},
+
+		"control characters": {
+			pattern: []byte("\x1b[[{#}m{*}\x1b[[0m"),
+			text:    []byte("\x1b[31mLorem\x1b[0m"),
+			matches: [][]byte{[]byte("31"), []byte("Lorem")},
+		},
+		"symbol characters": {
+			pattern: []byte("\x02{_}\x03 \x1d{*}\x1f"),
+			text:    []byte("\x02a\x03 \x1dbc\x1f"),
+			matches: [][]byte{[]byte("a"), []byte("bc")},
+		},
+		"symbol characters in group": {
+			pattern: []byte("(\x04|\x05)\x06?"),
+			text:    []byte("\x05\x06?"),
+			matches: [][]byte{},
+		},
+		"repeated symbol characters": {
+			pattern: []byte("(\x1e_)<2>"),
+			text:    []byte("\x1ea\x1eb"),
+			matches: [][]byte{},
+		},
+		"symbol characters in class": {
+			pattern: []byte("{[\x01-\x07]}"),
+			text:    []byte("\x05"),
+			matches: [][]byte{[]byte("\x05")},
+		},
+		"symbol character mismatch": {
+			pattern: []byte("\x1f"),
+			text:    []byte("a"),
+		},
 	}
 
 	for name, tc := range tcs {
@@ -479,51 +1188,1196 @@ func FuzzMatch(f *testing.F) {
 	})
 }
 
-var (
-	benchresult1 [][]byte
-	benchresult2 [][][]byte
-	benchmarks   = map[string][][]byte{
-		"exact match": {
-			[]byte("Lorem ipsum dolor sit amet."),
-			[]byte("Lorem ipsum dolor sit amet."),
-			[]byte("Lorem ipsum dolor sit amet."),
+func TestMatchUTF8(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		matches [][]byte
+		bytes   [][]byte
+	}{
+		"swedish word": {
+			pattern: []byte("Hej {^}!"),
+			text:    []byte("Hej Åsa!"),
+			matches: [][]byte{[]byte("Åsa")},
+		},
+		"swedish characters": {
+			pattern: []byte("{_}.{_}.{_}"),
+			text:    []byte("å.ä.ö"),
+			matches: [][]byte{[]byte("å"), []byte("ä"), []byte("ö")},
+		},
+		"swedish word and character": {
+			pattern: []byte("{^} {_}r {*}."),
+			text:    []byte("Smörgåsbord är gött."),
+			matches: [][]byte{[]byte("Smörgåsbord"), []byte("ä"), []byte("gött")},
+		},
+		"cyrillic words": {
+			pattern: []byte("{^}, {^}!"),
+			text:    []byte("Привет, мир!"),
+			matches: [][]byte{[]byte("Привет"), []byte("мир")},
+		},
+		"cyrillic character": {
+			pattern: []byte("П{_}ивет"),
+			text:    []byte("Привет"),
+			matches: [][]byte{[]byte("р")},
+		},
+		"swedish class": {
+			pattern: []byte("Sm{[åäö]}rg{[^aeiou]}sbord"),
+			text:    []byte("Smörgåsbord"),
+			matches: [][]byte{[]byte("ö"), []byte("å")},
+		},
+		"swedish class range": {
+			pattern: []byte("{[ä-ö]}"),
+			text:    []byte("ö"),
+			matches: [][]byte{[]byte("ö")},
+		},
+		"cjk word": {
+			pattern: []byte("{^}。"),
+			text:    []byte("你好世界。"),
+			matches: [][]byte{[]byte("你好世界")},
+		},
+		"cjk characters": {
+			pattern: []byte("{_}好{_}界"),
+			text:    []byte("你好世界"),
+			matches: [][]byte{[]byte("你"), []byte("世")},
+		},
+		"digits": {
+			pattern: []byte("{^}"),
+			text:    []byte("٣٤٥"),
+			matches: [][]byte{[]byte("٣٤٥")},
 		},
-		"character match": {
-			[]byte("Lorem ipsum dolor sit amet."),
-			[]byte("Lorem ipsum do_or sit amet."),
-			[]byte("Lorem ipsum do.or sit amet."),
+		"invalid encoding": {
+			pattern: []byte("{_}-{_}"),
+			text:    []byte("\xff-a"),
+			matches: [][]byte{{0xff}, []byte("a")},
+			bytes:   [][]byte{{0xff}, []byte("a")},
 		},
-		"word match": {
-			[]byte("Lorem ipsum dolor sit amet."),
-			[]byte("Lorem ^ dolor sit amet."),
-			[]byte("Lorem [a-zA-Z0-9]+ dolor sit amet."),
+		"punctuation is no word": {
+			pattern: []byte("{^}"),
+			text:    []byte("…"),
 		},
-		"phrase match": {
-			[]byte("Lorem ipsum dolor sit amet."),
-			[]byte("Lorem ipsum dolor * amet."),
-			[]byte("Lorem ipsum dolor .+ amet."),
+		"phrase before group": {
+			pattern: []byte("{*}(_)_"),
+			text:    []byte("a世"),
+			bytes:   [][]byte{[]byte("a\xe4")},
 		},
-		"all specials": {
-			[]byte("Lorem ipsum dolor sit amet."),
-			[]byte("{Lorem} {^} do{_}or {*}."),
-			[]byte("(Lorem) ([a-zA-Z0-9]+) do(.)or (.+)."),
+		"phrase before class": {
+			pattern: []byte("{*}[^x]_"),
+			text:    []byte("a世"),
+			bytes:   [][]byte{[]byte("a\xe4")},
+		},
+		"greedy phrase before class": {
+			pattern: []byte("{*+}[^x]_"),
+			text:    []byte("a世界"),
+			matches: [][]byte{[]byte("a")},
+			bytes:   [][]byte{[]byte("a世\xe7")},
 		},
 	}
-)
-
-func BenchmarkMatch(b *testing.B) {
-	var r1 [][]byte
-	var r2 [][][]byte
 
-	for name, benchmark := range benchmarks {
-		b.Run(fmt.Sprintf("%s simpex", name), func(b *testing.B) {
-			sx, _ := simpex.Compile(benchmark[1])
-			for i := 0; i < b.N; i++ {
-				r1 = sx.Match(benchmark[0])
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.CompileOptions{UTF8: true}.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
 			}
-		})
 
-		b.Run(fmt.Sprintf("%s regexp", name), func(b *testing.B) {
+			if matches := sx.Match(tc.text); !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"Match(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+
+			// Without UTF-8 mode, bytes are matched instead of runes.
+			sx, _ = simpex.Compile(tc.pattern)
+
+			if matches := sx.Match(tc.text); !reflect.DeepEqual(tc.bytes, matches) {
+				t.Fatalf(
+					"Match(%q, %q) = %q, want %q without UTF-8",
+					tc.pattern, tc.text, matches, tc.bytes,
+				)
+			}
+		})
+	}
+}
+
+func TestMatchCaseInsensitive(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		utf8    bool
+		matches [][]byte
+	}{
+		"mismatch": {
+			pattern: []byte("Lorem {^}"),
+			text:    []byte("dolor sit"),
+		},
+
+		"literal": {
+			pattern: []byte("LOREM ipsum"),
+			text:    []byte("lorem IPSUM"),
+			matches: [][]byte{},
+		},
+		"captures keep case": {
+			pattern: []byte("lorem {IPSUM} {^}"),
+			text:    []byte("Lorem Ipsum Dolor"),
+			matches: [][]byte{[]byte("Ipsum"), []byte("Dolor")},
+		},
+		"phrase edges": {
+			pattern: []byte("{*} SAYS, {*}."),
+			text:    []byte("Tobias says, Hello."),
+			matches: [][]byte{[]byte("Tobias"), []byte("Hello")},
+		},
+		"non-letters": {
+			pattern: []byte("[[100%]]"),
+			text:    []byte("[100%]"),
+			matches: [][]byte{},
+		},
+		"ascii only without utf-8": {
+			pattern: []byte("Åsa"),
+			text:    []byte("åSA"),
+		},
+		"utf-8": {
+			pattern: []byte("Hej {ÅSA}!"),
+			text:    []byte("hej åsa!"),
+			utf8:    true,
+			matches: [][]byte{[]byte("åsa")},
+		},
+		"utf-8 different sizes": {
+			pattern: []byte("{*}k"),
+			text:    []byte("Temperature 300\u212a"),
+			utf8:    true,
+			matches: [][]byte{[]byte("Temperature 300")},
+		},
+		"class": {
+			pattern: []byte("[A-C]-[^x]"),
+			text:    []byte("b-X"),
+		},
+		"class negated": {
+			pattern: []byte("[^A-C]"),
+			text:    []byte("b"),
+		},
+		"class utf-8": {
+			pattern: []byte("[åäö]"),
+			text:    []byte("Ä"),
+			utf8:    true,
+			matches: [][]byte{},
+		},
+		"class utf-8 different sizes": {
+			pattern: []byte("[k]"),
+			text:    []byte("\u212a"),
+			utf8:    true,
+			matches: [][]byte{},
+		},
+		"utf-8 cyrillic": {
+			pattern: []byte("ПРИВЕТ, {^}!"),
+			text:    []byte("привет, Мир!"),
+			utf8:    true,
+			matches: [][]byte{[]byte("Мир")},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			opts := simpex.CompileOptions{UTF8: tc.utf8, CaseInsensitive: true}
+
+			sx, err := opts.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			if matches := sx.Match(tc.text); !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"Match(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+func TestMatchLooseSpaces(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		sx      []byte
+		matches [][]byte
+	}{
+		"mismatch": {
+			pattern: []byte("Lorem {^}"),
+			text:    []byte("Lorem"),
+			sx:      []byte("Lorem\x01\x02\x1e\x03"),
+		},
+
+		"single spaces": {
+			pattern: []byte("Lorem {^}"),
+			text:    []byte("Lorem \t ipsum"),
+			sx:      []byte("Lorem\x01\x02\x1e\x03"),
+			matches: [][]byte{[]byte("ipsum")},
+		},
+		"runs of spaces": {
+			pattern: []byte("Name:    {^}   HP:  {#}"),
+			text:    []byte("Name: Bob HP:\t100"),
+			sx:      []byte("Name:\x01\x02\x1e\x03\x01HP:\x01\x02\x1c\x03"),
+			matches: [][]byte{[]byte("Bob"), []byte("100")},
+		},
+		"captured spaces": {
+			pattern: []byte("Lorem{ }ipsum"),
+			text:    []byte("Lorem  ipsum"),
+			sx:      []byte("Lorem\x02\x01\x03ipsum"),
+			matches: [][]byte{[]byte("  ")},
+		},
+		"space symbols": {
+			pattern: []byte("Lorem ~ipsum"),
+			text:    []byte("Lorem \tipsum"),
+			sx:      []byte("Lorem\x01\x01ipsum"),
+			matches: [][]byte{},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			opts := simpex.CompileOptions{LooseSpaces: true}
+
+			sx, err := opts.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			if string(tc.sx) != string(simpex.Program(sx)) {
+				t.Fatalf(
+					"Compile(%q)\ngot  %q\nwant %q",
+					tc.pattern, simpex.Program(sx), tc.sx,
+				)
+			}
+
+			if matches := sx.Match(tc.text); !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"Match(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+func TestMatchWords(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		opts    simpex.CompileOptions
+		matches [][]byte
+	}{
+		"default mismatch": {
+			pattern: []byte("Hello {^}!"),
+			text:    []byte("Hello Jean-Luc!"),
+		},
+
+		"extra characters": {
+			pattern: []byte("{^} and {^} and {^}"),
+			text:    []byte("Jean-Luc and o'Neil and snake_case"),
+			opts:    simpex.CompileOptions{WordChars: "-'_"},
+			matches: [][]byte{
+				[]byte("Jean-Luc"),
+				[]byte("o'Neil"),
+				[]byte("snake_case"),
+			},
+		},
+		"extra characters backtracking": {
+			pattern: []byte("{^}-{^}"),
+			text:    []byte("Jean-Luc-Picard"),
+			opts:    simpex.CompileOptions{WordChars: "-"},
+			matches: [][]byte{[]byte("Jean-Luc"), []byte("Picard")},
+		},
+		"extra runes": {
+			pattern: []byte("Hello {^}!"),
+			text:    []byte("Hello o’Neil!"),
+			opts:    simpex.CompileOptions{UTF8: true, WordChars: "’"},
+			matches: [][]byte{[]byte("o’Neil")},
+		},
+		"extra bytes": {
+			pattern: []byte("Hello {^}!"),
+			text:    []byte("Hello o’Neil!"),
+			opts:    simpex.CompileOptions{WordChars: "’"},
+			matches: [][]byte{[]byte("o’Neil")},
+		},
+		"predicate": {
+			pattern: []byte("{^}42"),
+			text:    []byte("Room42"),
+			opts:    simpex.CompileOptions{IsWord: unicode.IsLetter},
+			matches: [][]byte{[]byte("Room")},
+		},
+		"predicate mismatch": {
+			pattern: []byte("Hello {^}!"),
+			text:    []byte("Hello R2D2!"),
+			opts:    simpex.CompileOptions{IsWord: unicode.IsLetter},
+		},
+		"predicate with extra characters": {
+			pattern: []byte("Hello {^}!"),
+			text:    []byte("Hello Jean-Luc!"),
+			opts: simpex.CompileOptions{
+				IsWord:    unicode.IsUpper,
+				WordChars: "-eanuc",
+			},
+			matches: [][]byte{[]byte("Jean-Luc")},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := tc.opts.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			if matches := sx.Match(tc.text); !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"Match(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+func TestSubexpNames(t *testing.T) {
+	sx, err := simpex.Compile([]byte("{?first:^} {^} {{{?last:^}}} {?Say:: *}"))
+	if err != nil {
+		t.Fatalf("Compile() unexpected error '%s'", err)
+	}
+
+	want := []string{"first", "", "", ""}
+	if names := sx.SubexpNames(); !reflect.DeepEqual(want, names) {
+		t.Fatalf("SubexpNames() = %q, want %q", names, want)
+	}
+}
+
+func TestMatchNamed(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		matches map[string][]byte
+	}{
+		"mismatch": {
+			pattern: []byte("{?first:^} {?last:^}"),
+			text:    []byte("Lorem ipsum dolor"),
+		},
+
+		"unnamed captures": {
+			pattern: []byte("{^} {^}"),
+			text:    []byte("Lorem ipsum"),
+			matches: map[string][]byte{},
+		},
+		"escaped names": {
+			pattern: []byte("{?Say:: *} {?a__b::_}"),
+			text:    []byte("?Say: hello ?a_b:c"),
+			matches: map[string][]byte{},
+		},
+		"unmarked names": {
+			pattern: []byte("{Say: *}"),
+			text:    []byte("Say: hello"),
+			matches: map[string][]byte{},
+		},
+		"named captures": {
+			pattern: []byte("{?first:^} {^} {?last:*}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: map[string][]byte{
+				"first": []byte("Lorem"),
+				"last":  []byte("dolor sit amet."),
+			},
+		},
+		"optional named captures": {
+			pattern: []byte("{?first:^}( {?last:^})?"),
+			text:    []byte("Lorem"),
+			matches: map[string][]byte{
+				"first": []byte("Lorem"),
+				"last":  nil,
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			matches := sx.MatchNamed(tc.text)
+
+			if !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"MatchNamed(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+func TestMatchIndex(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		indexes []int
+	}{
+		"mismatch": {
+			pattern: []byte("Lorem ipsum"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+
+		"exact match simple": {
+			pattern: []byte("Lorem ipsum dolor sit amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			indexes: []int{0, 27},
+		},
+		"exact match empty": {
+			pattern: []byte(""),
+			text:    []byte(""),
+			indexes: []int{0, 0},
+		},
+		"empty capture": {
+			pattern: []byte("Lorem {}ipsum dolor sit amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			indexes: []int{0, 27, 6, 6},
+		},
+		"combination match capture": {
+			pattern: []byte("{Lorem} {^} do{_}or {*}."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			indexes: []int{0, 27, 0, 5, 6, 11, 14, 15, 18, 26},
+		},
+		"alternation capture not taken": {
+			pattern: []byte("({Lorem}|{ipsum}) dolor"),
+			text:    []byte("ipsum dolor"),
+			indexes: []int{0, 11, -1, -1, 0, 5},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			indexes := sx.MatchIndex(tc.text)
+
+			if !reflect.DeepEqual(tc.indexes, indexes) {
+				t.Fatalf(
+					"MatchIndex(%q, %q) = %v, want %v",
+					tc.pattern, tc.text, indexes, tc.indexes,
+				)
+			}
+		})
+	}
+}
+
+func TestMatchInto(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		dst     [][]byte
+		matches [][]byte
+	}{
+		"mismatch": {
+			pattern: []byte("Lorem {^}"),
+			text:    []byte("dolor sit"),
+			dst:     make([][]byte, 0, 1),
+		},
+
+		"no captures": {
+			pattern: []byte("Lorem ^"),
+			text:    []byte("Lorem ipsum"),
+			matches: [][]byte{},
+		},
+		"nil dst": {
+			pattern: []byte("{^} {*}."),
+			text:    []byte("Lorem ipsum dolor."),
+			matches: [][]byte{[]byte("Lorem"), []byte("ipsum dolor")},
+		},
+		"reused dst": {
+			pattern: []byte("{^} {*}."),
+			text:    []byte("Lorem ipsum dolor."),
+			dst:     [][]byte{[]byte("sit"), []byte("amet"), []byte("consectetur")},
+			matches: [][]byte{[]byte("Lorem"), []byte("ipsum dolor")},
+		},
+		"many captures": {
+			pattern: []byte("{L}{^} {i}{^} {d}{^} {s}{^} {a}{^}."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{
+				[]byte("L"), []byte("orem"),
+				[]byte("i"), []byte("psum"),
+				[]byte("d"), []byte("olor"),
+				[]byte("s"), []byte("it"),
+				[]byte("a"), []byte("met"),
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			matches := sx.MatchInto(tc.dst, tc.text)
+
+			if !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"MatchInto(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+
+			// Captures are views of the text, which appending to them
+			// mustn't overwrite.
+			for _, match := range matches {
+				if cap(match) != len(match) {
+					t.Fatalf(
+						"MatchInto(%q, %q) capture %q has room to grow",
+						tc.pattern, tc.text, match,
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchIntoAllocs(t *testing.T) {
+	tcs := map[string]struct {
+		pattern  []byte
+		text     []byte
+		mismatch bool
+	}{
+		"literal": {
+			pattern: []byte("Lorem ipsum dolor sit amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+		"character": {
+			pattern: []byte("Lorem {_}psum dolor sit amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+		"word": {
+			pattern: []byte("Lorem {^} dolor {^} amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+		"phrase": {
+			pattern: []byte("Lorem {*} sit {*}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+		"combination": {
+			pattern: []byte("{Lorem} {^} do{_}or {*}."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+		"mismatch": {
+			pattern:  []byte("Lorem {^} dolor"),
+			text:     []byte("Lorem ipsum sit"),
+			mismatch: true,
+		},
+		"backtracking": {
+			pattern: []byte("{*} is {^}."),
+			text:    []byte("this is what it is Bob."),
+		},
+		"backtracking mismatch": {
+			pattern:  []byte("{*} is {^}."),
+			text:     []byte("this is what it is, Bob."),
+			mismatch: true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			dst := make([][]byte, 0, 8)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				if (sx.MatchInto(dst, tc.text) == nil) != tc.mismatch {
+					t.Fatalf("MatchInto(%q, %q) unexpected result", tc.pattern, tc.text)
+				}
+			})
+			if allocs != 0 {
+				t.Fatalf(
+					"MatchInto(%q, %q) allocated %v times, want 0",
+					tc.pattern, tc.text, allocs,
+				)
+			}
+		})
+	}
+}
+
+func TestFindIndex(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		indexes []int
+	}{
+		"mismatch": {
+			pattern: []byte("consectetur"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+
+		"find middle": {
+			pattern: []byte("dolor"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			indexes: []int{12, 17},
+		},
+		"find captures": {
+			pattern: []byte("{^} {*}t."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			indexes: []int{0, 27, 0, 5, 6, 25},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			indexes := sx.FindIndex(tc.text)
+
+			if !reflect.DeepEqual(tc.indexes, indexes) {
+				t.Fatalf(
+					"FindIndex(%q, %q) = %v, want %v",
+					tc.pattern, tc.text, indexes, tc.indexes,
+				)
+			}
+		})
+	}
+}
+
+func TestFindIndexDuration(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+	}{
+		"repeated capture": {
+			pattern: []byte("(a)<1,1000>b"),
+			text:    append(bytes.Repeat([]byte("a"), 1000), 'c'),
+		},
+		"repeated branches": {
+			pattern: []byte("(_|_)<1,1000>x"),
+			text:    append(bytes.Repeat([]byte("a"), 1000), 'c'),
+		},
+		"repeated character": {
+			pattern: []byte("_<1,1000>x"),
+			text:    append(bytes.Repeat([]byte("a"), 1000), 'c'),
+		},
+		"phrase in long text": {
+			pattern: []byte("{*} failed: {*}."),
+			text:    bytes.Repeat([]byte("INFO request handled in 5ms\n"), 10000),
+		},
+		"greedy phrase in long text": {
+			pattern: []byte("{*+} failed: {*}."),
+			text:    bytes.Repeat([]byte("INFO request handled in 5ms\n"), 10000),
+		},
+		"phrases in long text": {
+			pattern: []byte("{*} {*}x"),
+			text:    bytes.Repeat([]byte("INFO request handled in 5ms\n"), 10000),
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			start := time.Now()
+
+			if indexes := sx.FindIndex(tc.text); indexes != nil {
+				t.Fatalf("FindIndex(%q, ...) = %v, want nil", tc.pattern, indexes)
+			}
+
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("FindIndex(%q, ...) took %s", tc.pattern, elapsed)
+			}
+		})
+	}
+}
+
+func TestFindAllIndex(t *testing.T) {
+	sx, err := simpex.Compile([]byte("{^}m"))
+	if err != nil {
+		t.Fatalf("Compile() unexpected error '%s'", err)
+	}
+
+	text := []byte("Lorem ipsum dolor sit amet.")
+	want := [][]int{{0, 5, 0, 4}, {6, 11, 6, 10}, {22, 24, 22, 23}}
+
+	if indexes := sx.FindAllIndex(text, -1); !reflect.DeepEqual(want, indexes) {
+		t.Fatalf("FindAllIndex(%q, -1) = %v, want %v", text, indexes, want)
+	}
+}
+
+func TestFind(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		matches [][]byte
+	}{
+		"mismatch": {
+			pattern: []byte("consectetur"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+		},
+
+		"find whole text": {
+			pattern: []byte("Lorem ipsum dolor sit amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("Lorem ipsum dolor sit amet.")},
+		},
+		"find beginning": {
+			pattern: []byte("Lorem"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("Lorem")},
+		},
+		"find middle": {
+			pattern: []byte("dolor"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("dolor")},
+		},
+		"find end": {
+			pattern: []byte("amet."),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("amet.")},
+		},
+		"find first occurrence": {
+			pattern: []byte("o{_}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("or"), []byte("r")},
+		},
+		"find empty": {
+			pattern: []byte(""),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{{}},
+		},
+
+		"find character capture": {
+			pattern: []byte("d{_}l"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("dol"), []byte("o")},
+		},
+		"find word capture": {
+			pattern: []byte("ipsum {^}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("ipsum dolor"), []byte("dolor")},
+		},
+		"find phrase capture": {
+			pattern: []byte("ipsum {*} amet"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("ipsum dolor sit amet"), []byte("dolor sit")},
+		},
+		"find trailing phrase capture": {
+			pattern: []byte("dolor {*}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("dolor sit amet."), []byte("sit amet.")},
+		},
+		"find bounded trailing phrase": {
+			pattern: []byte("dolor {*<3,5>}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("dolor sit"), []byte("sit")},
+		},
+		"find bounded greedy trailing phrase": {
+			pattern: []byte("dolor {*+<3,5>}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("dolor sit a"), []byte("sit a")},
+		},
+		"find bounded trailing phrase reaching end": {
+			pattern: []byte("sit {*<3,10>}"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			matches: [][]byte{[]byte("sit amet."), []byte("amet.")},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			matches := sx.Find(tc.text)
+
+			if !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"Find(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	tcs := map[string]struct {
+		pattern []byte
+		opts    simpex.CompileOptions
+		text    []byte
+		n       int
+		matches [][][]byte
+	}{
+		"mismatch": {
+			pattern: []byte("consectetur"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			n:       -1,
+		},
+
+		"find all": {
+			pattern: []byte("{^}m"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			n:       -1,
+			matches: [][][]byte{
+				{[]byte("Lorem"), []byte("Lore")},
+				{[]byte("ipsum"), []byte("ipsu")},
+				{[]byte("am"), []byte("a")},
+			},
+		},
+		"find all limited": {
+			pattern: []byte("{^}m"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			n:       1,
+			matches: [][][]byte{
+				{[]byte("Lorem"), []byte("Lore")},
+			},
+		},
+		"find all none": {
+			pattern: []byte("{^}m"),
+			text:    []byte("Lorem ipsum dolor sit amet."),
+			n:       0,
+		},
+		"find all non-overlapping": {
+			pattern: []byte("aba"),
+			text:    []byte("ababa aba"),
+			n:       -1,
+			matches: [][][]byte{
+				{[]byte("aba")},
+				{[]byte("aba")},
+			},
+		},
+		"find all empty": {
+			pattern: []byte(""),
+			text:    []byte("ab"),
+			n:       -1,
+			matches: [][][]byte{{{}}, {{}}, {{}}},
+		},
+		"find all empty abutting": {
+			pattern: []byte("{}a"),
+			text:    []byte("xax"),
+			n:       -1,
+			matches: [][][]byte{{[]byte("a"), {}}},
+		},
+		"find all empty between runes": {
+			pattern: []byte("(x)?"),
+			opts:    simpex.CompileOptions{UTF8: true},
+			text:    []byte("åä"),
+			n:       -1,
+			matches: [][][]byte{{{}}, {{}}, {{}}},
+		},
+		"find all bounded trailing phrase": {
+			pattern: []byte("a{*<1,2>}"),
+			text:    []byte("abcabcab"),
+			n:       -1,
+			matches: [][][]byte{
+				{[]byte("ab"), []byte("b")},
+				{[]byte("ab"), []byte("b")},
+				{[]byte("ab"), []byte("b")},
+			},
+		},
+		"find all bounded greedy trailing phrase": {
+			pattern: []byte("a{*+<1,2>}"),
+			text:    []byte("abcabcab"),
+			n:       -1,
+			matches: [][][]byte{
+				{[]byte("abc"), []byte("bc")},
+				{[]byte("abc"), []byte("bc")},
+				{[]byte("ab"), []byte("b")},
+			},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := tc.opts.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			matches := sx.FindAll(tc.text, tc.n)
+
+			if !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"FindAll(%q, %q, %d) = %q, want %q",
+					tc.pattern, tc.text, tc.n, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+// FuzzMatchRegexp verifies that Simpex.Match agrees with the regexp package on
+// the equivalent regular expression, for both matching and captures.
+func FuzzMatchRegexp(f *testing.F) {
+	f.Add([]byte("{*} is {*}."), []byte("this is what it is."))
+	f.Add([]byte("{*}, {^}!"), []byte("Well, well, hello!"))
+	f.Add([]byte("{^}a{^}"), []byte("banana1"))
+	f.Add(
+		[]byte("{Lorem} {^} do{_}or {*}."),
+		[]byte("Lorem ipsum dolor sit amet."),
+	)
+	f.Add([]byte("You (hit|miss) the {^}."), []byte("You miss the rat."))
+	f.Add([]byte("{(a|ab)}(c|{b^})"), []byte("abc"))
+	f.Add([]byte("{*}( {^})?."), []byte("a b."))
+	f.Add([]byte("HP: {#}{_}"), []byte("HP: -12.5%"))
+	f.Add([]byte("{*}~{^}~ {_}"), []byte("a b \t c  d"))
+	f.Add([]byte("You go {[nsewud]}{[^a-c]}."), []byte("You go ex."))
+	f.Add([]byte("{_<2,3>}{(a|[bc])<1,2>} {^<1,3>}"), []byte("xyzab c d"))
+	f.Add([]byte("{*+}, {*<2,>}, {*<0,3>}"), []byte("a, b, c, d, e"))
+	f.Add([]byte("\x1b[[{#}m{(\x1e_)<2>}\x06?"), []byte("\x1b[1m\x1ea\x1eb\x06?"))
+
+	f.Fuzz(func(t *testing.T, pattern, text []byte) {
+		// Regexp matches runes rather than bytes, so stick to ASCII.
+		if !isascii(pattern) || !isascii(text) {
+			t.Skip()
+		}
+
+		sx, err := simpex.Compile(pattern)
+		if err != nil {
+			t.Skip()
+		}
+
+		compareRegexp(t, sx, pattern, text)
+	})
+}
+
+// FuzzMatchRegexpUTF8 is like FuzzMatchRegexp but in UTF-8 mode.
+func FuzzMatchRegexpUTF8(f *testing.F) {
+	f.Add([]byte("Hej {^}!"), []byte("Hej Åsa!"))
+	f.Add([]byte("{_}{*}"), []byte("Привет мир"))
+	f.Add([]byte("{^}{_}"), []byte("你好世界。"))
+	f.Add([]byte("{*}_"), []byte("\xff\xe4\xbd"))
+	f.Add([]byte("{*}[^x]_"), []byte("a世"))
+	f.Add([]byte("{*+}(_)_"), []byte("a世界"))
+
+	f.Fuzz(func(t *testing.T, pattern, text []byte) {
+		// Regexp needs valid UTF-8 patterns, but handles any text.
+		if !utf8.Valid(pattern) {
+			t.Skip()
+		}
+
+		sx, err := simpex.CompileOptions{UTF8: true}.Compile(pattern)
+		if err != nil {
+			t.Skip()
+		}
+
+		compareRegexp(t, sx, pattern, text)
+	})
+}
+
+// FuzzMatchRegexpFold is like FuzzMatchRegexpUTF8 but case-insensitive.
+func FuzzMatchRegexpFold(f *testing.F) {
+	f.Add([]byte("HEJ {^}!"), []byte("hej Åsa!"))
+	f.Add([]byte("{*} SAYS {*}"), []byte("Tobias says hi"))
+	f.Add([]byte("{*}k"), []byte("300\u212a"))
+
+	f.Fuzz(func(t *testing.T, pattern, text []byte) {
+		if !utf8.Valid(pattern) {
+			t.Skip()
+		}
+
+		opts := simpex.CompileOptions{UTF8: true, CaseInsensitive: true}
+
+		sx, err := opts.Compile(pattern)
+		if err != nil {
+			t.Skip()
+		}
+
+		compareRegexp(t, sx, pattern, text)
+	})
+}
+
+// compareRegexp fails the test unless matching a text against a Simpex gives
+// the same results as its equivalent regular expression.
+func compareRegexp(t *testing.T, sx simpex.Simpex, pattern, text []byte) {
+	re := regexp.MustCompile(toregexp(sx))
+
+	matches := sx.Match(text)
+	want := re.FindSubmatch(text)
+
+	if want == nil && matches == nil {
+		return
+	} else if want == nil || matches == nil {
+		t.Fatalf(
+			"Match(%q, %q) = %q, regexp %q = %q",
+			pattern, text, matches, re, want,
+		)
+	}
+
+	if fmt.Sprintf("%q", matches) != fmt.Sprintf("%q", want[1:]) {
+		t.Fatalf(
+			"Match(%q, %q) = %q, regexp %q = %q",
+			pattern, text, matches, re, want[1:],
+		)
+	}
+
+	indexes := sx.MatchIndex(text)
+	wantIndexes := re.FindSubmatchIndex(text)
+
+	if !reflect.DeepEqual(indexes, wantIndexes) {
+		t.Fatalf(
+			"MatchIndex(%q, %q) = %v, regexp %q = %v",
+			pattern, text, indexes, re, wantIndexes,
+		)
+	}
+}
+
+// toregexp translates a compiled Simpex into an equivalent regular expression.
+func toregexp(sx simpex.Simpex) string {
+	var b strings.Builder
+
+	word := "[a-zA-Z0-9]+"
+	if simpex.Options(sx).UTF8 {
+		word = "[\\p{L}\\p{Nd}]+"
+	}
+
+	if simpex.Options(sx).CaseInsensitive {
+		b.WriteString("(?i)")
+	}
+
+	b.WriteString("^")
+
+	// Literals are quoted as a whole, not to split up multi-byte runes.
+	var literal []byte
+
+	classes, phrases := 0, 0
+
+	for _, in := range simpex.Instructions(sx) {
+		if in.Literal != nil {
+			literal = append(literal, in.Literal...)
+			continue
+		}
+
+		var symbol string
+
+		switch in.Symbol {
+		case '\x02':
+			symbol = "("
+		case '\x03':
+			symbol = ")"
+		case '\x04':
+			symbol = "(?:"
+		case '\x05':
+			symbol = "|"
+		case '\x06':
+			symbol = ")"
+		case '\x1f':
+			symbol = "(?s:.)"
+		case '\x1e':
+			symbol = word
+		case '\x1d':
+			greedy, low, high := simpex.Phrase(sx, phrases)
+			phrases++
+
+			symbol = fmt.Sprintf("(?s:.{%d,", low)
+			if high >= 0 {
+				symbol += fmt.Sprint(high)
+			}
+
+			symbol += "})"
+			if !greedy {
+				symbol = strings.TrimSuffix(symbol, ")") + "?)"
+			}
+		case '\x01':
+			symbol = `[ \t]+`
+		case '\x07':
+			negated, ranges := simpex.Class(sx, classes)
+			classes++
+
+			symbol = "["
+			if negated {
+				symbol += "^"
+			}
+
+			for i := 0; i < len(ranges); i += 2 {
+				symbol += fmt.Sprintf(`\x{%x}-\x{%x}`, ranges[i], ranges[i+1])
+			}
+
+			symbol += "]"
+		case '\x1c':
+			symbol = `[-+]?[0-9]+(?:\.[0-9]+)?`
+		}
+
+		b.WriteString(regexp.QuoteMeta(string(literal)))
+		b.WriteString(symbol)
+		literal = nil
+	}
+
+	b.WriteString(regexp.QuoteMeta(string(literal)))
+	b.WriteString("$")
+
+	return b.String()
+}
+
+func isascii(bs []byte) bool {
+	for _, b := range bs {
+		if b >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+var (
+	benchresult1 [][]byte
+	benchresult2 [][][]byte
+	benchmarks   = map[string][][]byte{
+		"exact match": {
+			[]byte("Lorem ipsum dolor sit amet."),
+			[]byte("Lorem ipsum dolor sit amet."),
+			[]byte("Lorem ipsum dolor sit amet."),
+		},
+		"character match": {
+			[]byte("Lorem ipsum dolor sit amet."),
+			[]byte("Lorem ipsum do_or sit amet."),
+			[]byte("Lorem ipsum do.or sit amet."),
+		},
+		"word match": {
+			[]byte("Lorem ipsum dolor sit amet."),
+			[]byte("Lorem ^ dolor sit amet."),
+			[]byte("Lorem [a-zA-Z0-9]+ dolor sit amet."),
+		},
+		"phrase match": {
+			[]byte("Lorem ipsum dolor sit amet."),
+			[]byte("Lorem ipsum dolor * amet."),
+			[]byte("Lorem ipsum dolor .+ amet."),
+		},
+		"all specials": {
+			[]byte("Lorem ipsum dolor sit amet."),
+			[]byte("{Lorem} {^} do{_}or {*}."),
+			[]byte("(Lorem) ([a-zA-Z0-9]+) do(.)or (.+)."),
+		},
+	}
+)
+
+func BenchmarkMatch(b *testing.B) {
+	var r1 [][]byte
+	var r2 [][][]byte
+
+	for name, benchmark := range benchmarks {
+		b.Run(fmt.Sprintf("%s simpex", name), func(b *testing.B) {
+			sx, _ := simpex.Compile(benchmark[1])
+			for i := 0; i < b.N; i++ {
+				r1 = sx.Match(benchmark[0])
+			}
+		})
+
+		b.Run(fmt.Sprintf("%s regexp", name), func(b *testing.B) {
 			pattern, _ := regexp.Compile(string(benchmark[2]))
 			for i := 0; i < b.N; i++ {
 				r2 = pattern.FindAllSubmatch(benchmark[0], -1)
@@ -534,3 +2388,48 @@ func BenchmarkMatch(b *testing.B) {
 	benchresult1 = r1
 	benchresult2 = r2
 }
+
+var benchresult3 []simpex.SetMatch
+
+func BenchmarkSet(b *testing.B) {
+	var patterns [][]byte
+
+	for i := 0; i < 1000; i++ {
+		patterns = append(patterns,
+			[]byte(fmt.Sprintf("Lorem %d ipsum {^} dolor {*}.", i)),
+			[]byte(fmt.Sprintf("{*} sit amet %d.", i)),
+			[]byte(fmt.Sprintf("{^} consectetur %d {*}", i)),
+		)
+	}
+
+	text := []byte("Lorem 500 ipsum dolor dolor sit amet.")
+
+	var r1 [][]byte
+	var r3 []simpex.SetMatch
+
+	b.Run("set", func(b *testing.B) {
+		set, _ := simpex.CompileSet(patterns)
+		for i := 0; i < b.N; i++ {
+			r3 = set.Match(text)
+		}
+	})
+
+	b.Run("loop", func(b *testing.B) {
+		var sxs []simpex.Simpex
+		for _, pattern := range patterns {
+			sx, _ := simpex.Compile(pattern)
+			sxs = append(sxs, sx)
+		}
+
+		for i := 0; i < b.N; i++ {
+			for _, sx := range sxs {
+				if m := sx.Match(text); m != nil {
+					r1 = m
+				}
+			}
+		}
+	})
+
+	benchresult1 = r1
+	benchresult3 = r3
+}
@@ -0,0 +1,92 @@
+package simpex
+
+import (
+	"strconv"
+	"unsafe"
+)
+
+// MatchString is like Match() but for strings. This is a convenience wrapper
+// for CompileString() and Simpex.MatchString().
+func MatchString(pattern, text string) ([]string, error) {
+	sx, err := CompileString(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return sx.MatchString(text), nil
+}
+
+// CompileString is like Compile() but for strings.
+func CompileString(pattern string) (Simpex, error) {
+	return CompileOptions{}.CompileString(pattern)
+}
+
+// CompileString is like the global CompileString(), but with the given
+// options.
+func (opts CompileOptions) CompileString(pattern string) (Simpex, error) {
+	// Compile() copies whatever it keeps of the pattern, so it can be
+	// given a view of the string rather than a copy.
+	return opts.Compile(view(pattern))
+}
+
+// ValidateString is like Validate() but for strings.
+func ValidateString(pattern string) []*CompileError {
+	return CompileOptions{}.ValidateString(pattern)
+}
+
+// ValidateString is like the global ValidateString(), but with the given
+// options.
+func (opts CompileOptions) ValidateString(pattern string) []*CompileError {
+	return opts.Validate(view(pattern))
+}
+
+// MustCompile is like Compile() but panics if the pattern doesn't compile. It
+// simplifies safe initialization of global variables holding patterns.
+func MustCompile(pattern []byte) Simpex {
+	sx, err := Compile(pattern)
+	if err != nil {
+		panic(`simpex: Compile(` + strconv.Quote(string(pattern)) + `): ` + err.Error())
+	}
+
+	return sx
+}
+
+// MustCompileString is like MustCompile() but for strings.
+func MustCompileString(pattern string) Simpex {
+	sx, err := CompileString(pattern)
+	if err != nil {
+		panic(`simpex: CompileString(` + strconv.Quote(pattern) + `): ` + err.Error())
+	}
+
+	return sx
+}
+
+// MatchString is like Match() but for strings. Captures are substrings of the
+// text rather than copies, so matching allocates nothing per capture. Those
+// in branches of groups not taken are empty.
+func (sx Simpex) MatchString(text string) []string {
+	var buf [stackIndexes]int
+
+	indexes := sx.matchIndex(view(text), sx.stackIndexes(buf[:]))
+	if indexes == nil {
+		return nil
+	}
+
+	matches := make([]string, 0, len(indexes)/2-1)
+	for i := 2; i < len(indexes); i += 2 {
+		if indexes[i] < 0 {
+			matches = append(matches, "")
+			continue
+		}
+
+		matches = append(matches, text[indexes[i]:indexes[i+1]])
+	}
+
+	return matches
+}
+
+// view returns the bytes of a string without copying them. They must never be
+// modified, which matching and compiling never do.
+func view(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
@@ -0,0 +1,118 @@
+package simpex_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tobiassjosten/go-simpex"
+)
+
+func TestMatchString(t *testing.T) {
+	tcs := map[string]struct {
+		pattern string
+		text    string
+		matches []string
+		err     bool
+	}{
+		"invalid pattern": {
+			pattern: "Lorem {^",
+			text:    "Lorem ipsum",
+			err:     true,
+		},
+		"mismatch": {
+			pattern: "Lorem {^}",
+			text:    "dolor sit",
+		},
+
+		"no captures": {
+			pattern: "Lorem ^",
+			text:    "Lorem ipsum",
+			matches: []string{},
+		},
+		"captures": {
+			pattern: "{^} {*}.",
+			text:    "Lorem ipsum dolor.",
+			matches: []string{"Lorem", "ipsum dolor"},
+		},
+		"empty": {
+			pattern: "",
+			text:    "",
+			matches: []string{},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			matches, err := simpex.MatchString(tc.pattern, tc.text)
+			if tc.err != (err != nil) {
+				t.Fatalf("MatchString(%q, %q) unexpected error '%v'", tc.pattern, tc.text, err)
+			}
+
+			if !reflect.DeepEqual(tc.matches, matches) {
+				t.Fatalf(
+					"MatchString(%q, %q) = %q, want %q",
+					tc.pattern, tc.text, matches, tc.matches,
+				)
+			}
+		})
+	}
+}
+
+func TestMatchStringOptions(t *testing.T) {
+	sx, err := simpex.CompileOptions{UTF8: true}.CompileString("Hej {^}!")
+	if err != nil {
+		t.Fatalf("CompileString(\"Hej {^}!\") unexpected error '%s'", err)
+	}
+
+	want := []string{"Åsa"}
+	if matches := sx.MatchString("Hej Åsa!"); !reflect.DeepEqual(want, matches) {
+		t.Fatalf("MatchString(\"Hej Åsa!\") = %q, want %q", matches, want)
+	}
+}
+
+func TestMatchStringAllocs(t *testing.T) {
+	sx := simpex.MustCompileString("Lorem {^} dolor {*}.")
+	text := "Lorem ipsum dolor sit amet."
+
+	// Only the slice of captures, however long they are.
+	allocs := testing.AllocsPerRun(100, func() {
+		sx.MatchString(text)
+	})
+	if allocs > 1 {
+		t.Fatalf("MatchString(%q) allocated %v times, want at most 1", text, allocs)
+	}
+}
+
+func TestMustCompile(t *testing.T) {
+	tcs := map[string]struct {
+		compile func()
+		panics  bool
+	}{
+		"bytes": {
+			compile: func() { simpex.MustCompile([]byte("Lorem {^}")) },
+		},
+		"bytes invalid": {
+			compile: func() { simpex.MustCompile([]byte("Lorem {^")) },
+			panics:  true,
+		},
+		"string": {
+			compile: func() { simpex.MustCompileString("Lorem {^}") },
+		},
+		"string invalid": {
+			compile: func() { simpex.MustCompileString("Lorem {^") },
+			panics:  true,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); tc.panics != (r != nil) {
+					t.Fatalf("unexpected panic state '%v'", r)
+				}
+			}()
+
+			tc.compile()
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package simpex
+
+import "sync"
+
+// Trigger is a pattern with a handler to call for lines matching it. Its
+// fields configure it when added to Triggers and shouldn't be modified after
+// that, other than through the methods of Triggers.
+type Trigger struct {
+	// Handler is called with the captures of every matching line.
+	Handler func(captures [][]byte)
+
+	// Priority orders triggers matching the same line, from highest to
+	// lowest. Triggers of equal priority go in the order they were added.
+	Priority int
+
+	// Groups lets triggers be enabled and disabled together.
+	Groups []string
+
+	// Once removes the trigger after it has fired the first time.
+	Once bool
+
+	// Stop keeps lines matching the trigger from propagating to the
+	// triggers following it.
+	Stop bool
+
+	// Disabled triggers are skipped until enabled.
+	Disabled bool
+
+	sx      Simpex
+	removed bool
+}
+
+// Triggers is a registry of triggers, dispatching lines to the handlers of
+// those matching them. The zero value is ready to use and it's safe for
+// concurrent use, including by the handlers it calls.
+type Triggers struct {
+	mu       sync.Mutex
+	triggers []*Trigger
+	disabled map[string]bool
+}
+
+// Add a trigger for lines matching a pattern, returning it for later use with
+// other methods.
+func (ts *Triggers) Add(pattern []byte, trigger Trigger) (*Trigger, error) {
+	sx, err := Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.AddSimpex(sx, trigger), nil
+}
+
+// AddSimpex is like Add() but for an already compiled pattern, like one
+// compiled with CompileOptions.
+func (ts *Triggers) AddSimpex(sx Simpex, trigger Trigger) *Trigger {
+	t := &trigger
+	t.sx = sx
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	// Keep triggers ordered by priority, after any of the same priority.
+	i := len(ts.triggers)
+	for i > 0 && ts.triggers[i-1].Priority < t.Priority {
+		i--
+	}
+
+	ts.triggers = append(ts.triggers, nil)
+	copy(ts.triggers[i+1:], ts.triggers[i:])
+	ts.triggers[i] = t
+
+	return t
+}
+
+// Remove a trigger, so it's never dispatched to again.
+func (ts *Triggers) Remove(t *Trigger) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.remove(t)
+}
+
+func (ts *Triggers) remove(t *Trigger) {
+	t.removed = true
+
+	for i, tt := range ts.triggers {
+		if tt == t {
+			ts.triggers = append(ts.triggers[:i], ts.triggers[i+1:]...)
+			break
+		}
+	}
+}
+
+// Enable a disabled trigger.
+func (ts *Triggers) Enable(t *Trigger) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	t.Disabled = false
+}
+
+// Disable a trigger, so it's skipped until enabled again.
+func (ts *Triggers) Disable(t *Trigger) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	t.Disabled = true
+}
+
+// EnableGroup enables a disabled group of triggers. Triggers that are
+// themselves disabled, or belong to other disabled groups, stay disabled.
+func (ts *Triggers) EnableGroup(group string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	delete(ts.disabled, group)
+}
+
+// DisableGroup disables a group of triggers, so they're skipped until the
+// group is enabled again.
+func (ts *Triggers) DisableGroup(group string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.disabled == nil {
+		ts.disabled = map[string]bool{}
+	}
+
+	ts.disabled[group] = true
+}
+
+// Dispatch a line to the enabled triggers matching it, in order of priority,
+// until one of them stops it from propagating. Returns the number of triggers
+// fired.
+//
+// Handlers are called without holding any locks, so they may add, remove,
+// enable, and disable triggers. Such changes take effect for the triggers not
+// yet reached by the ongoing dispatch.
+func (ts *Triggers) Dispatch(line []byte) int {
+	ts.mu.Lock()
+	triggers := append([]*Trigger{}, ts.triggers...)
+	ts.mu.Unlock()
+
+	fired := 0
+
+	for _, t := range triggers {
+		if !ts.enabled(t) {
+			continue
+		}
+
+		captures := t.sx.Match(line)
+		if captures == nil {
+			continue
+		}
+
+		// Make sure a trigger firing once does so only once, even when
+		// dispatching concurrently.
+		if t.Once {
+			ts.mu.Lock()
+			removed := t.removed
+			ts.remove(t)
+			ts.mu.Unlock()
+
+			if removed {
+				continue
+			}
+		}
+
+		if t.Handler != nil {
+			t.Handler(captures)
+		}
+
+		fired++
+
+		if t.Stop {
+			break
+		}
+	}
+
+	return fired
+}
+
+// enabled tells whether a trigger should be dispatched to.
+func (ts *Triggers) enabled(t *Trigger) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if t.removed || t.Disabled {
+		return false
+	}
+
+	for _, group := range t.Groups {
+		if ts.disabled[group] {
+			return false
+		}
+	}
+
+	return true
+}
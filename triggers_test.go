@@ -0,0 +1,243 @@
+package simpex_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/tobiassjosten/go-simpex"
+)
+
+// recorder collects the names and captures of fired triggers.
+type recorder struct {
+	mu    sync.Mutex
+	fired []string
+}
+
+func (r *recorder) handler(name string) func([][]byte) {
+	return func(captures [][]byte) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.fired = append(r.fired, name)
+		for _, capture := range captures {
+			r.fired = append(r.fired, string(capture))
+		}
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	tcs := map[string]struct {
+		triggers map[string]simpex.Trigger
+		patterns map[string]string
+		lines    []string
+		fired    []string
+	}{
+		"mismatch": {
+			patterns: map[string]string{"a": "Lorem {^}"},
+			triggers: map[string]simpex.Trigger{"a": {}},
+			lines:    []string{"dolor sit"},
+		},
+
+		"captures": {
+			patterns: map[string]string{"a": "Lorem {^} {^}"},
+			triggers: map[string]simpex.Trigger{"a": {}},
+			lines:    []string{"Lorem ipsum dolor"},
+			fired:    []string{"a", "ipsum", "dolor"},
+		},
+		"priority": {
+			patterns: map[string]string{"a": "{*}", "b": "*", "c": "*"},
+			triggers: map[string]simpex.Trigger{
+				"a": {Priority: 1},
+				"b": {Priority: 3},
+				"c": {Priority: 2},
+			},
+			lines: []string{"Lorem"},
+			fired: []string{"b", "c", "a", "Lorem"},
+		},
+		"stop propagation": {
+			patterns: map[string]string{"a": "*", "b": "*", "c": "*"},
+			triggers: map[string]simpex.Trigger{
+				"a": {Priority: 1},
+				"b": {Priority: 2, Stop: true},
+				"c": {Priority: 3},
+			},
+			lines: []string{"Lorem"},
+			fired: []string{"c", "b"},
+		},
+		"stop propagation only when matching": {
+			patterns: map[string]string{"a": "*", "b": "ipsum"},
+			triggers: map[string]simpex.Trigger{
+				"a": {Priority: 1},
+				"b": {Priority: 2, Stop: true},
+			},
+			lines: []string{"Lorem"},
+			fired: []string{"a"},
+		},
+		"once": {
+			patterns: map[string]string{"a": "*", "b": "*"},
+			triggers: map[string]simpex.Trigger{
+				"a": {Priority: 1, Once: true},
+				"b": {Priority: 2},
+			},
+			lines: []string{"Lorem", "ipsum"},
+			fired: []string{"b", "a", "b"},
+		},
+		"disabled": {
+			patterns: map[string]string{"a": "*", "b": "*"},
+			triggers: map[string]simpex.Trigger{
+				"a": {Priority: 1, Disabled: true},
+				"b": {Priority: 2},
+			},
+			lines: []string{"Lorem"},
+			fired: []string{"b"},
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			var ts simpex.Triggers
+			r := &recorder{}
+
+			// Add in a fixed order, for equal priorities to be stable.
+			for _, name := range []string{"a", "b", "c"} {
+				trigger, ok := tc.triggers[name]
+				if !ok {
+					continue
+				}
+
+				trigger.Handler = r.handler(name)
+
+				pattern := []byte(tc.patterns[name])
+				if _, err := ts.Add(pattern, trigger); err != nil {
+					t.Fatalf("Add(%q) unexpected error '%s'", pattern, err)
+				}
+			}
+
+			for _, line := range tc.lines {
+				ts.Dispatch([]byte(line))
+			}
+
+			if !reflect.DeepEqual(tc.fired, r.fired) {
+				t.Fatalf("fired %q, want %q", r.fired, tc.fired)
+			}
+		})
+	}
+}
+
+func TestTriggersAddError(t *testing.T) {
+	var ts simpex.Triggers
+
+	if _, err := ts.Add([]byte("{^"), simpex.Trigger{}); err == nil {
+		t.Fatalf("Add() missing error")
+	}
+}
+
+func TestTriggersAddSimpex(t *testing.T) {
+	var ts simpex.Triggers
+	r := &recorder{}
+
+	opts := simpex.CompileOptions{CaseInsensitive: true, LooseSpaces: true}
+	sx, err := opts.CompileString("LOREM {^}")
+	if err != nil {
+		t.Fatalf("CompileString() unexpected error '%s'", err)
+	}
+
+	ts.AddSimpex(sx, simpex.Trigger{Handler: r.handler("a")})
+	_, _ = ts.Add([]byte("LOREM {^}"), simpex.Trigger{Handler: r.handler("b"), Priority: 1})
+
+	ts.Dispatch([]byte("lorem   ipsum"))
+
+	if want := []string{"a", "ipsum"}; !reflect.DeepEqual(want, r.fired) {
+		t.Fatalf("fired %q, want %q", r.fired, want)
+	}
+}
+
+func TestTriggersState(t *testing.T) {
+	var ts simpex.Triggers
+	r := &recorder{}
+
+	a, _ := ts.Add([]byte("*"), simpex.Trigger{
+		Handler: r.handler("a"),
+		Groups:  []string{"x"},
+	})
+	b, _ := ts.Add([]byte("*"), simpex.Trigger{
+		Handler: r.handler("b"),
+		Groups:  []string{"x", "y"},
+	})
+
+	steps := []struct {
+		change func()
+		fired  int
+	}{
+		{change: func() {}, fired: 2},
+		{change: func() { ts.Disable(a) }, fired: 1},
+		{change: func() { ts.DisableGroup("y") }, fired: 0},
+		{change: func() { ts.Enable(a) }, fired: 1},
+		{change: func() { ts.EnableGroup("y") }, fired: 2},
+		{change: func() { ts.DisableGroup("x") }, fired: 0},
+		{change: func() { ts.EnableGroup("x") }, fired: 2},
+		{change: func() { ts.Remove(b) }, fired: 1},
+	}
+
+	for i, step := range steps {
+		step.change()
+
+		if fired := ts.Dispatch([]byte("Lorem")); fired != step.fired {
+			t.Fatalf("step %d: Dispatch() = %d, want %d", i, fired, step.fired)
+		}
+	}
+}
+
+func TestTriggersFromHandler(t *testing.T) {
+	var ts simpex.Triggers
+	r := &recorder{}
+
+	var b *simpex.Trigger
+
+	_, _ = ts.Add([]byte("enable"), simpex.Trigger{
+		Priority: 2,
+		Handler:  func([][]byte) { ts.Enable(b) },
+	})
+	b, _ = ts.Add([]byte("*"), simpex.Trigger{
+		Priority: 1,
+		Handler:  r.handler("b"),
+		Disabled: true,
+	})
+	_, _ = ts.Add([]byte("*"), simpex.Trigger{
+		Handler: func([][]byte) {
+			_, _ = ts.Add([]byte("*"), simpex.Trigger{Handler: r.handler("c")})
+		},
+		Once: true,
+	})
+
+	ts.Dispatch([]byte("Lorem"))
+	ts.Dispatch([]byte("enable"))
+	ts.Dispatch([]byte("ipsum"))
+
+	want := []string{"b", "c", "b", "c"}
+	if !reflect.DeepEqual(want, r.fired) {
+		t.Fatalf("fired %q, want %q", r.fired, want)
+	}
+}
+
+func TestTriggersOnceConcurrently(t *testing.T) {
+	var ts simpex.Triggers
+	r := &recorder{}
+
+	_, _ = ts.Add([]byte("*"), simpex.Trigger{Handler: r.handler("a"), Once: true})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ts.Dispatch([]byte("Lorem"))
+		}()
+	}
+	wg.Wait()
+
+	if want := []string{"a"}; !reflect.DeepEqual(want, r.fired) {
+		t.Fatalf("fired %q, want %q", r.fired, want)
+	}
+}
@@ -0,0 +1,202 @@
+package simpex
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNoMatch is returned by Unmarshal() when the text doesn't match.
+	ErrNoMatch = errors.New("text doesn't match pattern")
+
+	// ErrUnsupportedType is wrapped by an UnmarshalError when a capture is
+	// assigned to a field of a type it can't be converted into.
+	ErrUnsupportedType = errors.New("unsupported type")
+
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// UnmarshalError describes a capture that couldn't be converted into the type
+// of the struct field it was assigned to.
+type UnmarshalError struct {
+	Field string       // Name of the struct field.
+	Type  reflect.Type // Type of the struct field.
+	Value []byte       // The captured text.
+	Err   error        // Why it couldn't be converted.
+}
+
+func (err *UnmarshalError) Error() string {
+	return fmt.Sprintf(
+		"cannot unmarshal '%s' into field %s of type %s: %s",
+		err.Value, err.Field, err.Type, err.Err,
+	)
+}
+
+func (err *UnmarshalError) Unwrap() error {
+	return err.Err
+}
+
+// Unmarshal matches a text against a pattern and stores its captures in the
+// struct pointed to by v. Fields tagged with `simpex:"name"` are given the
+// capture with that name and the remaining exported fields are given the
+// remaining captures in order, as are those with empty tags. Fields tagged
+// with `simpex:"-"` are skipped.
+//
+// Captures are converted into the types of their fields, which can be
+// strings, byte slices, booleans, integers, floats, time.Duration, or anything
+// implementing encoding.TextUnmarshaler, along with pointers to those. If a
+// capture can't be converted, an *UnmarshalError is returned. If the text
+// doesn't match, ErrNoMatch is returned.
+func (sx Simpex) Unmarshal(text []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal into %T, need a struct pointer", v)
+	}
+
+	rv = rv.Elem()
+
+	// Map fields to captures up front, so that invalid tags are reported
+	// whether the text matches or not. Named fields go first, to leave the
+	// remaining captures for the rest.
+	fields := map[int]int{}
+	claimed := map[int]bool{}
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+
+		name := field.Tag.Get("simpex")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		index := sx.subexpIndex(name)
+		if index < 0 {
+			return fmt.Errorf(
+				"no capture named '%s' for field %s",
+				name, field.Name,
+			)
+		}
+
+		fields[i] = index
+		claimed[index] = true
+	}
+
+	for i, position := 0, 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+
+		if field.Tag.Get("simpex") != "" || !field.IsExported() {
+			continue
+		}
+
+		for claimed[position] {
+			position++
+		}
+
+		if position < len(sx.names) {
+			fields[i] = position
+			position++
+		}
+	}
+
+	matches := sx.Match(text)
+	if matches == nil {
+		return ErrNoMatch
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		index, ok := fields[i]
+		if !ok || matches[index] == nil {
+			continue
+		}
+
+		if err := unmarshal(matches[index], rv.Field(i)); err != nil {
+			return &UnmarshalError{
+				Field: rv.Type().Field(i).Name,
+				Type:  rv.Type().Field(i).Type,
+				Value: matches[index],
+				Err:   err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// unmarshal converts a capture into the type of v and stores it there.
+func unmarshal(capture []byte, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return unmarshal(capture, v.Elem())
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText(capture)
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(string(capture))
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(string(capture))
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return ErrUnsupportedType
+		}
+
+		v.SetBytes(capture)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(string(capture))
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(string(capture), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(string(capture), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(capture), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+
+	default:
+		return ErrUnsupportedType
+	}
+
+	return nil
+}
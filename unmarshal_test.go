@@ -0,0 +1,189 @@
+package simpex_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tobiassjosten/go-simpex"
+)
+
+type vitals struct {
+	Health int
+	Mana   uint8
+	Ratio  float64
+	Name   string
+}
+
+type tagged struct {
+	Name    string `simpex:"name"`
+	Skipped string `simpex:"-"`
+	First   []byte
+	Second  *int
+	unexp   string
+}
+
+type converted struct {
+	Alive    bool
+	Duration time.Duration
+	Address  net.IP
+}
+
+func TestUnmarshal(t *testing.T) {
+	two := 2
+
+	tcs := map[string]struct {
+		pattern []byte
+		text    []byte
+		dst     any
+		want    any
+		err     error
+	}{
+		"mismatch": {
+			pattern: []byte("HP: {^}"),
+			text:    []byte("MP: 100"),
+			dst:     &vitals{},
+			want:    &vitals{},
+			err:     simpex.ErrNoMatch,
+		},
+
+		"by position": {
+			pattern: []byte("HP:{*} MP:{^} ratio:{*} name:{^}"),
+			text:    []byte("HP:-100 MP:200 ratio:0.5 name:Lorem"),
+			dst:     &vitals{},
+			want:    &vitals{Health: -100, Mana: 200, Ratio: 0.5, Name: "Lorem"},
+		},
+		"fewer captures than fields": {
+			pattern: []byte("HP:{^}"),
+			text:    []byte("HP:100"),
+			dst:     &vitals{Name: "Lorem"},
+			want:    &vitals{Health: 100, Name: "Lorem"},
+		},
+		"by name and position": {
+			pattern: []byte("{^} {?name:^} {^}"),
+			text:    []byte("Lorem ipsum 2"),
+			dst:     &tagged{Skipped: "dolor"},
+			want: &tagged{
+				Name:    "ipsum",
+				Skipped: "dolor",
+				First:   []byte("Lorem"),
+				Second:  &two,
+			},
+		},
+		"empty tag": {
+			pattern: []byte("{?name:^} {^}"),
+			text:    []byte("Lorem ipsum"),
+			dst: &struct {
+				First  string `simpex:""`
+				Second string
+			}{},
+			want: &struct {
+				First  string `simpex:""`
+				Second string
+			}{First: "Lorem", Second: "ipsum"},
+		},
+		"bool, duration and text unmarshaler": {
+			pattern: []byte("{^} {*} {*}"),
+			text:    []byte("true 1m30s 127.0.0.1"),
+			dst:     &converted{},
+			want: &converted{
+				Alive:    true,
+				Duration: 90 * time.Second,
+				Address:  net.IPv4(127, 0, 0, 1),
+			},
+		},
+
+		"invalid integer": {
+			pattern: []byte("HP:{^}"),
+			text:    []byte("HP:full"),
+			dst:     &vitals{},
+			want:    &vitals{},
+			err:     strconv.ErrSyntax,
+		},
+		"overflowing integer": {
+			pattern: []byte("HP:{^} MP:{^}"),
+			text:    []byte("HP:100 MP:256"),
+			dst:     &vitals{},
+			want:    &vitals{Health: 100},
+			err:     strconv.ErrRange,
+		},
+		"unsupported type": {
+			pattern: []byte("{*}"),
+			text:    []byte("Lorem"),
+			dst:     &struct{ Words []string }{},
+			want:    &struct{ Words []string }{},
+			err:     simpex.ErrUnsupportedType,
+		},
+	}
+
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			sx, err := simpex.Compile(tc.pattern)
+			if err != nil {
+				t.Fatalf("Compile(%q) unexpected error '%s'", tc.pattern, err)
+			}
+
+			err = sx.Unmarshal(tc.text, tc.dst)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf(
+					"Unmarshal(%q, %q) error '%v', want '%v'",
+					tc.pattern, tc.text, err, tc.err,
+				)
+			}
+
+			if !reflect.DeepEqual(tc.want, tc.dst) {
+				t.Fatalf(
+					"Unmarshal(%q, %q) = %+v, want %+v",
+					tc.pattern, tc.text, tc.dst, tc.want,
+				)
+			}
+		})
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	sx, err := simpex.Compile([]byte("HP:{^}"))
+	if err != nil {
+		t.Fatalf("Compile() unexpected error '%s'", err)
+	}
+
+	err = sx.Unmarshal([]byte("HP:full"), &vitals{})
+
+	var uerr *simpex.UnmarshalError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Unmarshal() error '%v', want *UnmarshalError", err)
+	}
+
+	if uerr.Field != "Health" || string(uerr.Value) != "full" || uerr.Type.Kind() != reflect.Int {
+		t.Fatalf("Unmarshal() error %+v, want Health field with 'full'", uerr)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	sx, err := simpex.Compile([]byte("{?name:^}"))
+	if err != nil {
+		t.Fatalf("Compile() unexpected error '%s'", err)
+	}
+
+	tcs := map[string]any{
+		"nil":         nil,
+		"non-pointer": vitals{},
+		"nil pointer": (*vitals)(nil),
+		"non-struct":  new(int),
+		"unknown name": &struct {
+			Name string `simpex:"nom"`
+		}{},
+	}
+
+	for name, dst := range tcs {
+		t.Run(name, func(t *testing.T) {
+			if err := sx.Unmarshal([]byte("Lorem"), dst); err == nil {
+				t.Fatalf("Unmarshal(%T) missing error", dst)
+			}
+		})
+	}
+}